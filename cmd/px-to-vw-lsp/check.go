@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.lsp.dev/protocol"
+)
+
+// checkFinding is a raw px value reported by the check subcommand. Lines
+// and columns are 1-based, columns count unicode code points.
+type checkFinding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	EndColumn  int    `json:"endColumn"`
+	Value      string `json:"value"`
+	Suggestion string `json:"suggestion"`
+	Message    string `json:"message"`
+	Level      string `json:"level"`
+}
+
+// sarifLevels maps the cssrem `diagnostics` setting to SARIF result levels,
+// anything else including "off" fails the check as an error
+var sarifLevels = map[SchemaJsonDiagnostics]string{
+	SchemaJsonDiagnosticsWarning:     "warning",
+	SchemaJsonDiagnosticsInformation: "note",
+	SchemaJsonDiagnosticsHint:        "note",
+}
+
+// checkFile returns the raw px values of a file, using the same rules as
+// the raw px diagnostics
+func checkFile(path string, config *Config) ([]checkFinding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	level, ok := sarifLevels[config.Diagnostics]
+	if !ok {
+		level = "error"
+	}
+
+	doc := newDocument(protocol.LanguageIdentifier(languageForPath(path)), 0, string(data))
+	lines := doc.Lines()
+	var findings []checkFinding
+	for _, raw := range findRawPx(doc, config) {
+		line, match := lines[raw.line], raw.conversion.From
+		findings = append(findings, checkFinding{
+			File:       filepath.ToSlash(path),
+			Line:       raw.line + 1,
+			Column:     int(positionEncodingUTF32.character(line, match.Start)) + 1,
+			EndColumn:  int(positionEncodingUTF32.character(line, match.End)) + 1,
+			Value:      match.Text(),
+			Suggestion: raw.conversion.Text(),
+			Message:    raw.message(),
+			Level:      level,
+		})
+	}
+	return findings, nil
+}
+
+// runCheck implements `px-to-vw-lsp check [flags] [paths...]`. It exits
+// with 1 when raw px values are found and 2 when the check couldn't run.
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("check", "[flags] [paths...]",
+		"reports px values that should be vw in files and directories (default: the current directory)", stderr)
+	format := flags.String("format", "text", "output format (text, json, sarif)")
+	logLevel := flags.String("log-level", "warn", "log level (debug, info, warn, error)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	log = initLogger(*logLevel, "")
+
+	write, ok := checkFormats[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "px-to-vw-lsp: unknown format %q\n", *format)
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	targets, err := newConfigResolver().collectTargets(paths)
+	if err != nil {
+		fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+		return 2
+	}
+
+	findings := []checkFinding{}
+	for _, target := range targets {
+		found, err := checkFile(target.path, target.config)
+		if err != nil {
+			fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+			return 2
+		}
+		findings = append(findings, found...)
+	}
+
+	if err := write(stdout, findings); err != nil {
+		fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+		return 2
+	}
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+var checkFormats = map[string]func(io.Writer, []checkFinding) error{
+	"text":  writeCheckText,
+	"json":  writeCheckJSON,
+	"sarif": writeCheckSARIF,
+}
+
+// writeCheckText writes one `file:line:col: message` line per finding
+func writeCheckText(w io.Writer, findings []checkFinding) error {
+	for _, f := range findings {
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s\n", f.File, f.Line, f.Column, f.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCheckJSON(w io.Writer, findings []checkFinding) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(findings)
+}
+
+// sarif types, just the parts of SARIF 2.1.0 code scanning needs
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool     `json:"tool"`
+		ColumnKind string        `json:"columnKind"`
+		Results    []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndColumn   int `json:"endColumn"`
+	}
+)
+
+func writeCheckSARIF(w io.Writer, findings []checkFinding) error {
+	results := []sarifResult{}
+	for _, f := range findings {
+		uri := f.File
+		if filepath.IsAbs(f.File) {
+			uri = "file://" + f.File
+		}
+		results = append(results, sarifResult{
+			RuleID:  diagnosticRawPx,
+			Level:   f.Level,
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region:           sarifRegion{StartLine: f.Line, StartColumn: f.Column, EndColumn: f.EndColumn},
+			}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           diagnosticSource,
+				InformationURI: "https://github.com/meow-d/px-to-vw-lsp",
+				Rules: []sarifRule{{
+					ID:               diagnosticRawPx,
+					ShortDescription: sarifMessage{Text: "px value that should be converted to vw"},
+				}},
+			}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	})
+}
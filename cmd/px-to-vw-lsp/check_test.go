@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestRunCheck(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".cssrem":        `{"vwDesign": 1440, "fixedDigits": 3, "ignores": ["legacy/**"], "ignoresViaCommand": ["1px"], "allowedPxProperties": ["border-width"]}`,
+		"app.css":        ".a {\n  border: 1px solid;\n  border-width: 2px;\n  /* é */ width: 144px;\n}\n",
+		"legacy/old.css": ".b { width: 144px; }\n",
+		"clean.css":      ".c { width: 10vw; }\n",
+		"media.sass":     "@media (max-width: 768px)\n  .d\n    width: 10vw\n",
+		"range.css":      ".e { font-size: 14px..20px; }\n",
+	})
+	chdir(t, root)
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"Clean file", []string{"clean.css"}, 0},
+		{"Sass media query", []string{"media.sass"}, 0},
+		{"Range", []string{"range.css"}, 0},
+		{"Ignored file", []string{"legacy/old.css"}, 0},
+		{"Raw px", []string{"."}, 1},
+		{"Unknown format", []string{"--format", "xml"}, 2},
+		{"Missing path", []string{"missing.css"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCheck(tt.args, &stdout, &stderr); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d: %s%s", tt.expected, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	runCheck(nil, &stdout, &stderr)
+	// columns count code points, so the é counts once
+	expected := "app.css:4:18: Raw px value 144px, use 10.000vw\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestRunCheckJSON(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".cssrem": `{"vwDesign": 1440, "fixedDigits": 3, "diagnostics": "warning"}`,
+		"app.css": ".a { width: 144px; }",
+	})
+	chdir(t, root)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCheck([]string{"--format", "json"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+
+	var findings []checkFinding
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", stdout.String(), err)
+	}
+	expected := checkFinding{
+		File:       "app.css",
+		Line:       1,
+		Column:     13,
+		EndColumn:  18,
+		Value:      "144px",
+		Suggestion: "10.000vw",
+		Message:    "Raw px value 144px, use 10.000vw",
+		Level:      "warning",
+	}
+	if len(findings) != 1 || findings[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, findings)
+	}
+
+	// no findings is an empty array, not null
+	stdout.Reset()
+	if code := runCheck([]string{"--format", "json", filepath.Join(root, ".cssrem")}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if stdout.String() != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", stdout.String())
+	}
+}
+
+func TestRunCheckSARIF(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{"src/app.css": ".a { width: 144px; }"})
+	chdir(t, root)
+
+	var stdout, stderr bytes.Buffer
+	if code := runCheck([]string{"--format", "sarif"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("Expected exit code 1, got %d: %s", code, stderr.String())
+	}
+
+	var sarif sarifLog
+	if err := json.Unmarshal(stdout.Bytes(), &sarif); err != nil {
+		t.Fatalf("Invalid SARIF %q: %v", stdout.String(), err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF log: %+v", sarif)
+	}
+	results := sarif.Runs[0].Results
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %+v", results)
+	}
+	result := results[0]
+	if result.RuleID != diagnosticRawPx || result.Level != "error" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	location := result.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "src/app.css" || location.Region != (sarifRegion{StartLine: 1, StartColumn: 13, EndColumn: 18}) {
+		t.Errorf("Unexpected location: %+v", location)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/protocol"
+)
+
+// clampEdit returns the edit replacing a range of the document with its
+// clamp() expression. Ranges spanning lines are left alone.
+func clampEdit(doc *document, r convert.Range, converter *convert.Converter, encoding positionEncoding) (protocol.TextEdit, bool) {
+	_, index := doc.Scan()
+	lines := doc.Lines()
+	line := index.line(r.Start)
+	if index.line(r.End) != line {
+		return protocol.TextEdit{}, false
+	}
+	clamp, err := converter.Clamp(r.From, r.To)
+	if err != nil {
+		log.Sugar().Debugf("No clamp() for %s..%s: %v", r.From.Text(), r.To.Text(), err)
+		return protocol.TextEdit{}, false
+	}
+	return protocol.TextEdit{
+		Range:   encoding.lineRange(uint32(line), lines[line], r.Start-index[line], r.End-index[line]),
+		NewText: clamp,
+	}, true
+}
+
+// clampCompletions returns the clamp() completion of a `14px..20px` range
+// ending at the byte offset col of a line, reporting whether a range ends
+// there at all
+func clampCompletions(doc *document, line, col int, config *Config, encoding positionEncoding) ([]protocol.CompletionItem, bool) {
+	scan, index := doc.Scan()
+	r, ok := scan.RangeBefore(index.offset(line, col))
+	if !ok {
+		return nil, false
+	}
+	converter := config.converter()
+	edit, ok := clampEdit(doc, r, converter, encoding)
+	if !ok {
+		return []protocol.CompletionItem{}, true
+	}
+	return []protocol.CompletionItem{{
+		Kind:       protocol.CompletionItemKindFunction,
+		Label:      edit.NewText,
+		Detail:     clampDetail(r, converter, config),
+		FilterText: scan.Text[r.Start:r.End],
+		TextEdit:   &edit,
+	}}, true
+}
+
+// clampActions returns a code action turning the range at a cursor, or the
+// pair of px values in a selection, into a clamp() expression, reporting
+// whether there is such a range at all
+func clampActions(uri protocol.DocumentURI, doc *document, rng protocol.Range, config *Config, encoding positionEncoding) ([]protocol.CodeAction, bool) {
+	lines := doc.Lines()
+	if int(rng.End.Line) >= len(lines) {
+		return nil, false
+	}
+	scan, index := doc.Scan()
+	start := index.offset(int(rng.Start.Line), encoding.byteOffset(lines[rng.Start.Line], rng.Start.Character))
+	end := index.offset(int(rng.End.Line), encoding.byteOffset(lines[rng.End.Line], rng.End.Character))
+
+	var r convert.Range
+	var ok bool
+	if start == end {
+		r, ok = scan.RangeAt(start)
+	} else {
+		r, ok = scan.RangeIn(start, end)
+	}
+	if !ok {
+		return nil, false
+	}
+	edit, ok := clampEdit(doc, r, config.converter(), encoding)
+	if !ok {
+		return nil, true
+	}
+	return []protocol.CodeAction{convertAction(
+		fmt.Sprintf("Convert %s..%s → %s", r.From.Text(), r.To.Text(), edit.NewText),
+		uri, []protocol.TextEdit{edit},
+	)}, true
+}
+
+// clampDetail describes the viewport widths a clamp() scales between
+func clampDetail(r convert.Range, converter *convert.Converter, config *Config) string {
+	return fmt.Sprintf("fluid from %spx to %spx wide viewports",
+		strconv.FormatFloat(config.MinViewportWidth, 'f', -1, 64),
+		strconv.FormatFloat(converter.ViewportWidth(r.To), 'f', -1, 64))
+}
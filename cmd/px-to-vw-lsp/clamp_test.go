@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestClampCompletion(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		config        Config
+		expectLabels  []string
+		expectedStart uint32
+	}{
+		{
+			name:          "Range",
+			line:          "  font-size: 14px..20px",
+			config:        Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, RootFontSize: 16},
+			expectLabels:  []string{"clamp(14px, 0.563vw + 0.743rem, 20px)"},
+			expectedStart: 13,
+		},
+		{
+			// the second value alone would complete as 0.2px
+			name:         "Minimum viewport not below the viewport",
+			line:         "  font-size: 14px..20px",
+			config:       Config{ViewportWidth: 1440, MinViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, uri := newTestHandler(t, tt.line, tt.config)
+
+			list, err := handler.Completion(context.Background(), &protocol.CompletionParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+					Position:     protocol.Position{Line: 0, Character: uint32(len(tt.line))},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(list.Items) != len(tt.expectLabels) {
+				t.Fatalf("Expected %d items, got %+v", len(tt.expectLabels), list.Items)
+			}
+			for i, label := range tt.expectLabels {
+				item := list.Items[i]
+				if item.Label != label || item.TextEdit.NewText != label {
+					t.Errorf("Item %d: got %q, want %q", i, item.Label, label)
+				}
+				if item.TextEdit.Range.Start.Character != tt.expectedStart {
+					t.Errorf("Item %d range start: got %d, want %d", i, item.TextEdit.Range.Start.Character, tt.expectedStart)
+				}
+			}
+		})
+	}
+}
+
+func TestClampCodeAction(t *testing.T) {
+	text := ".title {\n  font-size: 14px..20px;\n  padding: 8px 12px;\n}"
+	config := Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, RootFontSize: 16}
+
+	tests := []struct {
+		name   string
+		rng    protocol.Range
+		title  string
+		edited protocol.Range
+	}{
+		{
+			name:   "Cursor in a range",
+			rng:    *rangeOf(1, 16, 1, 16),
+			title:  "Convert 14px..20px → clamp(14px, 0.563vw + 0.743rem, 20px)",
+			edited: *rangeOf(1, 13, 1, 23),
+		},
+		{
+			name:   "One character selection in a range",
+			rng:    *rangeOf(1, 16, 1, 17),
+			title:  "Convert 14px..20px → clamp(14px, 0.563vw + 0.743rem, 20px)",
+			edited: *rangeOf(1, 13, 1, 23),
+		},
+		{
+			name:   "Selected pair",
+			rng:    *rangeOf(2, 11, 2, 19),
+			title:  "Convert 8px..12px → clamp(8px, 0.376vw + 0.412rem, 12px)",
+			edited: *rangeOf(2, 11, 2, 19),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, uri := newTestHandler(t, text, config)
+
+			actions, err := handler.CodeAction(context.Background(), &protocol.CodeActionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Range:        tt.rng,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(actions) == 0 || actions[0].Title != tt.title {
+				t.Fatalf("Expected the first action %q, got %+v", tt.title, actions)
+			}
+			edits := actions[0].Edit.Changes[uri]
+			if len(edits) != 1 || edits[0].Range != tt.edited {
+				t.Errorf("Expected an edit of %v, got %v", tt.edited, edits)
+			}
+			// a range isn't offered as two separate conversions
+			for _, action := range actions[1:] {
+				if action.Title == "Convert .20px → 0.014vw" {
+					t.Errorf("Unexpected action %q", action.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestRangeNotConvertedAlone(t *testing.T) {
+	text := ".title {\n  font-size: 14px..20px;\n  padding: 8px;\n}"
+	config := Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsWarning}
+	handler, uri := newTestHandler(t, text, config)
+	doc, _ := handler.openDocument(uri)
+	ctx := context.Background()
+
+	// only 8px is a raw px value, the range converts to a clamp()
+	diagnostics := rawPxDiagnostics(doc, &config, positionEncodingUTF16)
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 2 {
+		t.Errorf("Expected a diagnostic on 8px, got %+v", diagnostics)
+	}
+
+	edits := pxToVwEdits(doc, nil, &config, positionEncodingUTF16)
+	if len(edits) != 1 || edits[0].NewText != "0.556vw" {
+		t.Errorf("Expected an edit of 8px, got %+v", edits)
+	}
+
+	hints, err := handler.inlayHints(ctx, &inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        protocol.Range{End: protocol.Position{Line: 3}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(hints) != 1 || hints[0].Label != "→ 0.556vw" {
+		t.Errorf("Expected a hint for 8px, got %+v", hints)
+	}
+
+	lenses, err := handler.CodeLens(ctx, &protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(lenses) != 1 || lenses[0].Command.Title != "1 px values left, convert → vw" {
+		t.Errorf("Expected a lens counting 8px, got %+v", lenses)
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configResolver resolves the effective config of files outside the server,
+// the way the server does for workspace folders: default < global < the
+// nearest .cssrem above the file
+type configResolver struct {
+	global *GlobalConfig
+	// roots caches the nearest directory with a .cssrem for each directory,
+	// empty when there is none
+	roots   map[string]string
+	configs map[string]*Config
+}
+
+func newConfigResolver() *configResolver {
+	return &configResolver{
+		global:  loadGlobalConfig(log),
+		roots:   map[string]string{},
+		configs: map[string]*Config{},
+	}
+}
+
+// resolve returns the project root and effective config for files in dir.
+// Without a .cssrem the root is empty and the global config applies.
+func (r *configResolver) resolve(dir string) (string, *Config) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", r.config("")
+	}
+	root := r.root(dir)
+	return root, r.config(root)
+}
+
+func (r *configResolver) root(dir string) string {
+	if root, ok := r.roots[dir]; ok {
+		return root
+	}
+
+	root := ""
+	if _, err := os.Stat(filepath.Join(dir, ".cssrem")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = r.root(parent)
+	}
+	r.roots[dir] = root
+	return root
+}
+
+func (r *configResolver) config(root string) *Config {
+	if config, ok := r.configs[root]; ok {
+		return config
+	}
+
+	var config Config
+	if root == "" {
+		config = mergeConfigs(loadDefaultConfig(), *r.global.Get(), Config{})
+	} else {
+		config = loadEffectiveConfig(r.global, root, log)
+	}
+	r.configs[root] = &config
+	return &config
+}
+
+// ignored reports whether path matches the `ignores` globs of its config.
+// Globs are relative to the directory of the .cssrem, or to base without one.
+func (r *configResolver) ignored(path, base string) bool {
+	root, config := r.resolve(filepath.Dir(path))
+	if len(config.Ignores) == 0 {
+		return false
+	}
+	if root == "" {
+		root = base
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return isIgnoredPath(filepath.ToSlash(rel), config)
+}
+
+// fileTarget is a file to process with its effective config
+type fileTarget struct {
+	path   string
+	config *Config
+}
+
+// collectTargets expands paths into the files to process. Directories are
+// walked like the workspace conversion walks folders, files given by name
+// are processed whatever their language unless they are ignored.
+func (r *configResolver) collectTargets(paths []string) ([]fileTarget, error) {
+	var targets []fileTarget
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			base, err := filepath.Abs(filepath.Dir(path))
+			if err != nil {
+				return nil, err
+			}
+			if !r.ignored(path, base) {
+				_, config := r.resolve(filepath.Dir(path))
+				targets = append(targets, fileTarget{path: path, config: config})
+			}
+			continue
+		}
+
+		base, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if file != path && (skippedDirs[d.Name()] || r.ignored(file, base)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			_, config := r.resolve(filepath.Dir(file))
+			if r.ignored(file, base) || !languageEnabled(languageForPath(file), config) {
+				return nil
+			}
+			targets = append(targets, fileTarget{path: file, config: config})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// newFlagSet returns the flag set of a subcommand, printing usage and
+// errors to stderr
+func newFlagSet(name, arguments, description string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: px-to-vw-lsp %s %s\n", name, arguments)
+		fmt.Fprintln(stderr, description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// runConvert implements `px-to-vw-lsp convert [flags] [paths...]`
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("convert", "[flags] [paths...]",
+		"converts px to vw in files and directories (default: the current directory)", stderr)
+	dryRun := flags.Bool("dry-run", false, "print a unified diff instead of writing files")
+	toStdout := flags.Bool("stdout", false, "print converted files to stdout instead of writing them")
+	logLevel := flags.String("log-level", "warn", "log level (debug, info, warn, error)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	log = initLogger(*logLevel, "")
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	targets, err := newConfigResolver().collectTargets(paths)
+	if err != nil {
+		fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	total, files := 0, 0
+	for _, target := range targets {
+		data, err := os.ReadFile(target.path)
+		if err != nil {
+			fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+			status = 1
+			continue
+		}
+
+		converted, count := target.config.converterFor(languageForPath(target.path)).ConvertText(string(data))
+		switch {
+		case *toStdout:
+			io.WriteString(stdout, converted)
+		case count == 0:
+		case *dryRun:
+			io.WriteString(stdout, unifiedDiff(target.path, string(data), converted))
+		default:
+			info, err := os.Stat(target.path)
+			if err == nil {
+				err = os.WriteFile(target.path, []byte(converted), info.Mode().Perm())
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+				status = 1
+				continue
+			}
+		}
+		if count > 0 {
+			total += count
+			files++
+		}
+	}
+
+	if !*toStdout {
+		verb := "Converted"
+		if *dryRun {
+			verb = "Would convert"
+		}
+		fmt.Fprintf(stderr, "%s %d px values in %d files\n", verb, total, files)
+	}
+	return status
+}
+
+// runFilter implements `px-to-vw-lsp filter [flags]`, converting stdin to
+// stdout for editors that pipe selections through shell commands
+func runFilter(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("filter", "[flags] < input.css",
+		"converts px to vw in stdin and writes the result to stdout", stderr)
+	path := flags.String("path", "", "path of the file being edited, to find its config (default: the current directory)")
+	reverse := flags.Bool("reverse", false, "convert vw to px instead")
+	logLevel := flags.String("log-level", "warn", "log level (debug, info, warn, error)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+	log = initLogger(*logLevel, "")
+
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+		return 1
+	}
+
+	dir := "."
+	if *path != "" {
+		dir = filepath.Dir(*path)
+	}
+	_, config := newConfigResolver().resolve(dir)
+	converter := config.converterFor(languageForPath(*path))
+
+	var output string
+	if *reverse {
+		output, _ = converter.ConvertTextToPx(string(input))
+	} else {
+		output, _ = converter.ConvertText(string(input))
+	}
+	if _, err := io.WriteString(stdout, output); err != nil {
+		fmt.Fprintf(stderr, "px-to-vw-lsp: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// diffContext is the number of unchanged lines around each hunk
+const diffContext = 3
+
+// unifiedDiff returns a unified diff between two versions of a file with
+// the same number of lines. Conversion never adds or removes lines, so the
+// lines of both versions pair up by index and no alignment is needed.
+func unifiedDiff(path, before, after string) string {
+	noEOL := !strings.HasSuffix(before, "\n")
+	oldLines := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	newLines := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+	var changed []int
+	for i := range oldLines {
+		if oldLines[i] != newLines[i] {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	path = filepath.ToSlash(path)
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	for i := 0; i < len(changed); {
+		start := max(changed[i]-diffContext, 0)
+		end := changed[i] + 1
+		// merge changes whose context overlaps into one hunk
+		for i++; i < len(changed) && changed[i]-end <= 2*diffContext; i++ {
+			end = changed[i] + 1
+		}
+		end = min(end+diffContext, len(oldLines))
+		writeHunk(&b, oldLines, newLines, start, end, noEOL)
+	}
+	return b.String()
+}
+
+// writeHunk writes lines start:end of both versions as a hunk
+func writeHunk(b *strings.Builder, oldLines, newLines []string, start, end int, noEOL bool) {
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+
+	last := len(oldLines) - 1
+	writeLine := func(prefix string, line string, i int) {
+		b.WriteString(prefix + line + "\n")
+		if noEOL && i == last {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+
+	// consecutive changed lines are written as a block of removals followed by additions
+	for i := start; i < end; {
+		if oldLines[i] == newLines[i] {
+			writeLine(" ", oldLines[i], i)
+			i++
+			continue
+		}
+		j := i
+		for j < end && oldLines[j] != newLines[j] {
+			j++
+		}
+		for k := i; k < j; k++ {
+			writeLine("-", oldLines[k], k)
+		}
+		for k := i; k < j; k++ {
+			writeLine("+", newLines[k], k)
+		}
+		i = j
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestFiles creates files relative to root
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunConvert(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"app.css":             ".a { width: 144px; border: 1px solid; }\n",
+		"legacy/old.css":      ".b { width: 144px; }\n",
+		"notes.txt":           "144px\n",
+		"node_modules/x.css":  ".c { width: 144px; }\n",
+		"mobile/.cssrem":      `{"vwDesign": 375, "fixedDigits": 2}`,
+		"mobile/page.scss":    ".d { width: 75px; }\n",
+		"mobile/page.sass":    "@media (max-width: 768px)\n  .f\n    width: 75px\n",
+		".cssrem":             `{"vwDesign": 1440, "fixedDigits": 3, "ignores": ["legacy/**"], "ignoresViaCommand": ["1px"]}`,
+		"mobile/legacy/x.css": ".e { width: 75px; }\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	if code := runConvert([]string{root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("convert exited with %d: %s", code, stderr.String())
+	}
+
+	expected := map[string]string{
+		"app.css":            ".a { width: 10.000vw; border: 1px solid; }\n",
+		"legacy/old.css":     ".b { width: 144px; }\n",
+		"notes.txt":          "144px\n",
+		"node_modules/x.css": ".c { width: 144px; }\n",
+		// the nearest .cssrem wins and has no ignores
+		"mobile/page.scss":    ".d { width: 20.00vw; }\n",
+		"mobile/legacy/x.css": ".e { width: 20.00vw; }\n",
+		// the indented @media condition is left alone
+		"mobile/page.sass": "@media (max-width: 768px)\n  .f\n    width: 20.00vw\n",
+	}
+	for name, want := range expected {
+		if got := readTestFile(t, filepath.Join(root, name)); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	if !strings.Contains(stderr.String(), "Converted 4 px values in 4 files") {
+		t.Errorf("Unexpected summary: %q", stderr.String())
+	}
+}
+
+func TestRunConvertStdout(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	path := filepath.Join(root, "notes.txt")
+	writeTestFiles(t, root, map[string]string{"notes.txt": "width: 720px;"})
+
+	var stdout, stderr bytes.Buffer
+	if code := runConvert([]string{"--stdout", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("convert exited with %d: %s", code, stderr.String())
+	}
+	// files given by name are converted whatever their extension
+	if stdout.String() != "width: 50.000vw;" {
+		t.Errorf("Unexpected output: %q", stdout.String())
+	}
+	if got := readTestFile(t, path); got != "width: 720px;" {
+		t.Errorf("--stdout modified the file: %q", got)
+	}
+}
+
+func TestRunConvertDryRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	content := ".a {\n  width: 144px;\n}\n\n\n\n\n\n\n\n.b {\n  height: 72px;\n}"
+	writeTestFiles(t, root, map[string]string{"app.css": content})
+
+	var stdout, stderr bytes.Buffer
+	if code := runConvert([]string{"--dry-run", root}, &stdout, &stderr); code != 0 {
+		t.Fatalf("convert exited with %d: %s", code, stderr.String())
+	}
+	if got := readTestFile(t, filepath.Join(root, "app.css")); got != content {
+		t.Errorf("--dry-run modified the file: %q", got)
+	}
+
+	path := filepath.ToSlash(filepath.Join(root, "app.css"))
+	expected := "--- a/" + path + "\n+++ b/" + path + "\n" +
+		"@@ -1,5 +1,5 @@\n" +
+		" .a {\n" +
+		"-  width: 144px;\n" +
+		"+  width: 10.000vw;\n" +
+		" }\n" +
+		" \n" +
+		" \n" +
+		"@@ -9,5 +9,5 @@\n" +
+		" \n" +
+		" \n" +
+		" .b {\n" +
+		"-  height: 72px;\n" +
+		"+  height: 5.000vw;\n" +
+		" }\n" +
+		"\\ No newline at end of file\n"
+	if stdout.String() != expected {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", stdout.String(), expected)
+	}
+	if !strings.Contains(stderr.String(), "Would convert 2 px values in 1 files") {
+		t.Errorf("Unexpected summary: %q", stderr.String())
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"mobile/.cssrem": `{"vwDesign": 375, "fixedDigits": 2, "ignoresViaCommand": ["1px"]}`,
+	})
+	chdir(t, root)
+
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Default config",
+			input:    "width: 144px;\nheight: 72px;\n",
+			expected: "width: 10.000vw;\nheight: 5.000vw;\n",
+		},
+		{
+			// the buffer doesn't need to exist on disk yet
+			name:     "Config of the path hint",
+			args:     []string{"--path", "mobile/new.scss"},
+			input:    "border: 1px solid; width: 75px;",
+			expected: "border: 1px solid; width: 20.00vw;",
+		},
+		{
+			// the bounds of a range only convert together, to a clamp()
+			name:     "Range",
+			input:    "font-size: 14px..20px; width: 144px;",
+			expected: "font-size: 14px..20px; width: 10.000vw;",
+		},
+		{
+			name:     "Reverse",
+			args:     []string{"--reverse", "--path", filepath.Join(root, "mobile", "page.scss")},
+			input:    "width: 20vw; margin: 8px;",
+			expected: "width: 75px; margin: 8px;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runFilter(tt.args, strings.NewReader(tt.input), &stdout, &stderr); code != 0 {
+				t.Fatalf("filter exited with %d: %s", code, stderr.String())
+			}
+			if stdout.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, stdout.String())
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runFilter([]string{"app.css"}, strings.NewReader(""), &stdout, &stderr); code != 2 {
+		t.Errorf("Expected exit code 2 for a positional argument, got %d", code)
+	}
+}
+
+func TestUnifiedDiffMergesNearbyHunks(t *testing.T) {
+	before := "a\n1px\nb\nc\nd\n2px\ne\n"
+	after := "a\n1vw\nb\nc\nd\n2vw\ne\n"
+	expected := "--- a/x.css\n+++ b/x.css\n" +
+		"@@ -1,7 +1,7 @@\n" +
+		" a\n-1px\n+1vw\n b\n c\n d\n-2px\n+2vw\n e\n"
+	if got := unifiedDiff("x.css", before, after); got != expected {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, expected)
+	}
+	if got := unifiedDiff("x.css", before, before); got != "" {
+		t.Errorf("Expected no diff for equal files, got %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/protocol"
+)
+
+func (h *Handler) CodeAction(ctx context.Context, params *protocol.CodeActionParams) ([]protocol.CodeAction, error) {
+	uri := params.TextDocument.URI
+	doc, err := h.openDocument(uri)
+	if err != nil {
+		return nil, err
+	}
+	lines := doc.Lines()
+	rng := params.Range
+	if rng.Start.Line > rng.End.Line || int(rng.Start.Line) >= len(lines) {
+		return nil, errInvalidParams("invalid range %v in %s of %d lines", rng, uri, len(lines))
+	}
+	h.trackCursor(uri, rng.Start.Line)
+
+	config := h.getConfigForDocument(uri)
+	converter := config.converter()
+	encoding := h.getPositionEncoding()
+	actions := rawPxQuickFixes(uri, doc, params.Context.Diagnostics, config, encoding)
+
+	// clients like Helix and Kakoune always select at least one character,
+	// so a selection within a single literal counts as the cursor on it
+	line := lines[rng.Start.Line]
+	match, ok := unitAt(doc, int(rng.Start.Line), encoding.byteOffset(line, rng.Start.Character))
+	cursor := rng.Start == rng.End ||
+		ok && rng.End.Line == rng.Start.Line && encoding.byteOffset(line, rng.End.Character) <= match.End
+	clampRange := rng
+	if cursor {
+		clampRange.End = rng.Start
+	}
+
+	clamps, inRange := clampActions(uri, doc, clampRange, config, encoding)
+	actions = append(actions, clamps...)
+
+	if cursor {
+		// the values of a range convert together, to a clamp()
+		if ok && !inRange && match.Unit == "px" && !converter.Ignored(match) && !converter.Excluded(match) {
+			conv := converter.PxToVwConversion(match)
+			actions = append(actions, convertAction(
+				fmt.Sprintf("Convert %s → %s", match.Text(), conv.Text()),
+				uri, []protocol.TextEdit{conversionEdit(rng.Start.Line, line, conv, converter, encoding)},
+			))
+		}
+	} else if edits := pxToVwEdits(doc, &rng, config, encoding); len(edits) > 0 {
+		actions = append(actions, convertAction(
+			fmt.Sprintf("Convert px → vw in selection (%d values)", len(edits)),
+			uri, edits,
+		))
+	}
+
+	if edits := pxToVwEdits(doc, nil, config, encoding); len(edits) > 0 {
+		actions = append(actions, convertAction(
+			fmt.Sprintf("Convert px → vw in document (%d values)", len(edits)),
+			uri, edits,
+		))
+	}
+
+	if err := h.checkModified(uri, doc); err != nil {
+		return nil, err
+	}
+	log.Sugar().Debugf("Code actions for %s at %v: %d", uri, rng, len(actions))
+	return actions, nil
+}
+
+func convertAction(title string, uri protocol.DocumentURI, edits []protocol.TextEdit) protocol.CodeAction {
+	return protocol.CodeAction{
+		Title: title,
+		Kind:  protocol.RefactorRewrite,
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits},
+		},
+	}
+}
+
+// pxToVwEdits returns edits converting every px literal inside rng, or the
+// whole document when rng is nil, skipping values in ignoresViaCommand
+func pxToVwEdits(doc *document, rng *protocol.Range, config *Config, encoding positionEncoding) []protocol.TextEdit {
+	lines := doc.Lines()
+	scan, index := doc.Scan()
+	edits := []protocol.TextEdit{}
+	for _, edit := range config.converter().PxToVwEditsOf(scan.Matches) {
+		i, edit := index.lineEdit(edit)
+		line, lineNum := lines[i], uint32(i)
+		if rng != nil && (lineNum < rng.Start.Line || lineNum > rng.End.Line) {
+			continue
+		}
+		if rng != nil && lineNum == rng.Start.Line && edit.Start < encoding.byteOffset(line, rng.Start.Character) {
+			continue
+		}
+		if rng != nil && lineNum == rng.End.Line && edit.End > encoding.byteOffset(line, rng.End.Character) {
+			continue
+		}
+		edits = append(edits, protocol.TextEdit{
+			Range:   encoding.lineRange(lineNum, line, edit.Start, edit.End),
+			NewText: edit.NewText,
+		})
+	}
+	return edits
+}
+
+// conversionEdit returns the edit replacing the converted literal on line lineNum
+func conversionEdit(lineNum uint32, line string, conv convert.Conversion, converter *convert.Converter, encoding positionEncoding) protocol.TextEdit {
+	return protocol.TextEdit{
+		Range:   encoding.lineRange(lineNum, line, conv.From.Start, conv.From.End),
+		NewText: converter.Replacement(conv),
+	}
+}
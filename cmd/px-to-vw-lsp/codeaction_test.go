@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestCodeAction(t *testing.T) {
+	text := ".card {\n  width: 348px;\n  border: 1px solid;\n  margin: 10px 20px;\n}"
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, IgnoresViaCommand: []string{"1px"}}
+
+	tests := []struct {
+		name         string
+		rng          protocol.Range
+		expectTitles []string
+		expectEdits  []int
+	}{
+		{
+			name: "Cursor on px value",
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 10},
+				End:   protocol.Position{Line: 1, Character: 10},
+			},
+			expectTitles: []string{"Convert 348px → 24.167vw", "Convert px → vw in document (3 values)"},
+			expectEdits:  []int{1, 3},
+		},
+		{
+			name: "Cursor on ignored value",
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 2, Character: 11},
+				End:   protocol.Position{Line: 2, Character: 11},
+			},
+			expectTitles: []string{"Convert px → vw in document (3 values)"},
+			expectEdits:  []int{3},
+		},
+		{
+			// Helix and Kakoune select the character under the cursor
+			name: "One character selection",
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 10},
+				End:   protocol.Position{Line: 1, Character: 11},
+			},
+			expectTitles: []string{"Convert 348px → 24.167vw", "Convert px → vw in document (3 values)"},
+			expectEdits:  []int{1, 3},
+		},
+		{
+			name: "Selected literal",
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 9},
+				End:   protocol.Position{Line: 1, Character: 14},
+			},
+			expectTitles: []string{"Convert 348px → 24.167vw", "Convert px → vw in document (3 values)"},
+			expectEdits:  []int{1, 3},
+		},
+		{
+			name: "Selection past a literal",
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 3, Character: 10},
+				End:   protocol.Position{Line: 3, Character: 17},
+			},
+			expectTitles: []string{"Convert px → vw in selection (1 values)", "Convert px → vw in document (3 values)"},
+			expectEdits:  []int{1, 3},
+		},
+		{
+			name: "Selection",
+			rng: protocol.Range{
+				Start: protocol.Position{Line: 2, Character: 0},
+				End:   protocol.Position{Line: 3, Character: 15},
+			},
+			expectTitles: []string{"Convert px → vw in selection (1 values)", "Convert px → vw in document (3 values)"},
+			expectEdits:  []int{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, uri := newTestHandler(t, text, config)
+
+			actions, err := handler.CodeAction(context.Background(), &protocol.CodeActionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Range:        tt.rng,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(actions) != len(tt.expectTitles) {
+				t.Fatalf("Expected %d actions, got %d: %v", len(tt.expectTitles), len(actions), actions)
+			}
+			for i, action := range actions {
+				if action.Title != tt.expectTitles[i] {
+					t.Errorf("Action %d title: got %q, want %q", i, action.Title, tt.expectTitles[i])
+				}
+				if edits := action.Edit.Changes[uri]; len(edits) != tt.expectEdits[i] {
+					t.Errorf("Action %d edits: got %d, want %d", i, len(edits), tt.expectEdits[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPxToVwEditsAddMark(t *testing.T) {
+	config := &Config{ViewportWidth: 1440, UnitPrecision: 3, AddMark: true}
+	edits := pxToVwEdits(newDocument("css", 1, "width: 348px;"), nil, config, positionEncodingUTF16)
+
+	if len(edits) != 1 {
+		t.Fatalf("Expected 1 edit, got %d", len(edits))
+	}
+	if edits[0].NewText != "24.167vw /* 348px */" {
+		t.Errorf("NewText: got %q, want %q", edits[0].NewText, "24.167vw /* 348px */")
+	}
+	if edits[0].Range.Start.Character != 7 || edits[0].Range.End.Character != 12 {
+		t.Errorf("Range: got %v", edits[0].Range)
+	}
+}
+
+func TestCodeActionInComment(t *testing.T) {
+	text := "/* sizes:\n   10px 20px */\n.a { width: 348px; }"
+	handler, uri := newTestHandler(t, text, Config{ViewportWidth: 1440, UnitPrecision: 3})
+
+	actions, err := handler.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 1, Character: 5},
+			End:   protocol.Position{Line: 1, Character: 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// only the document action, for the value outside the comment
+	if len(actions) != 1 || actions[0].Title != "Convert px → vw in document (1 values)" {
+		t.Fatalf("Unexpected actions: %v", actions)
+	}
+	if edit := actions[0].Edit.Changes[uri][0]; edit.Range.Start.Line != 2 || edit.Range.Start.Character != 12 {
+		t.Errorf("Unexpected edit range: %v", edit.Range)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// commandConvertBlock converts the px values of the block starting at a
+// position, with the document uri and the position as arguments
+const commandConvertBlock = "pxToVw.convertBlock"
+
+// CodeLens places a lens above each rule and at-rule block with px values
+// left, like cssrem does, whose command converts just that block. Nested
+// blocks count towards the blocks enclosing them.
+func (h *Handler) CodeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	uri := params.TextDocument.URI
+	doc, err := h.openDocument(uri)
+	if err != nil {
+		return nil, err
+	}
+	lines := doc.Lines()
+	scan, index := doc.Scan()
+	edits := h.getConfigForDocument(uri).converter().PxToVwEditsOf(scan.Matches)
+	encoding := h.getPositionEncoding()
+
+	lenses := []protocol.CodeLens{}
+	for _, block := range scan.Blocks() {
+		count := 0
+		for _, edit := range edits {
+			if block.Contains(edit.Start) {
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		pos := index.position(lines, block.Start, encoding)
+		lenses = append(lenses, protocol.CodeLens{
+			Range: protocol.Range{Start: pos, End: pos},
+			Command: &protocol.Command{
+				Title:     fmt.Sprintf("%d px values left, convert → vw", count),
+				Command:   commandConvertBlock,
+				Arguments: []interface{}{uri, pos},
+			},
+		})
+	}
+
+	if err := h.checkModified(uri, doc); err != nil {
+		return nil, err
+	}
+	log.Sugar().Debugf("Code lenses for %s: %d", uri, len(lenses))
+	return lenses, nil
+}
+
+// blockEdits returns the edits converting the px values of the block
+// starting at pos, or an InvalidParams error when no block starts there
+func (h *Handler) blockEdits(uri protocol.DocumentURI, pos protocol.Position) ([]protocol.TextEdit, error) {
+	doc, line, err := h.documentLine(uri, pos)
+	if err != nil {
+		return nil, err
+	}
+	lines := doc.Lines()
+	scan, index := doc.Scan()
+	encoding := h.getPositionEncoding()
+	offset := index.offset(int(pos.Line), encoding.byteOffset(line, pos.Character))
+
+	for _, block := range scan.Blocks() {
+		if block.Start != offset {
+			continue
+		}
+		rng := protocol.Range{
+			Start: index.position(lines, block.Start, encoding),
+			End:   index.position(lines, block.End, encoding),
+		}
+		return pxToVwEdits(doc, &rng, h.getConfigForDocument(uri), encoding), nil
+	}
+	return nil, errInvalidParams("no block at %v in %s", pos, uri)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestCodeLens(t *testing.T) {
+	text := "@media (min-width: 768px) {\n  .a { width: 144px; }\n  .b { color: red; }\n}\n.c { margin: 72px 1px; }"
+	handler, uri := newTestHandler(t, text, Config{ViewportWidth: 1440, UnitPrecision: 3, IgnoresViaCommand: []string{"1px"}})
+
+	lenses, err := handler.CodeLens(context.Background(), &protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the media condition is left alone, .b has nothing left
+	expected := []struct {
+		pos   protocol.Position
+		title string
+	}{
+		{protocol.Position{Line: 0, Character: 0}, "1 px values left, convert → vw"},
+		{protocol.Position{Line: 1, Character: 2}, "1 px values left, convert → vw"},
+		{protocol.Position{Line: 4, Character: 0}, "1 px values left, convert → vw"},
+	}
+	if len(lenses) != len(expected) {
+		t.Fatalf("Expected %d lenses, got %+v", len(expected), lenses)
+	}
+	for i, lens := range lenses {
+		if lens.Range.Start != expected[i].pos || lens.Command.Title != expected[i].title {
+			t.Errorf("Lens %d: got %v %q, want %v %q", i, lens.Range.Start, lens.Command.Title, expected[i].pos, expected[i].title)
+		}
+		if lens.Command.Command != commandConvertBlock {
+			t.Errorf("Lens %d: unexpected command %q", i, lens.Command.Command)
+		}
+	}
+}
+
+func TestConvertBlockCommand(t *testing.T) {
+	conn := &recordingConn{results: map[string]interface{}{
+		protocol.MethodWorkspaceApplyEdit: protocol.ApplyWorkspaceEditResponse{Applied: true},
+	}}
+	handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+	handler.configs["/project"] = &Config{ViewportWidth: 1440, UnitPrecision: 3}
+	uri := protocol.DocumentURI("file:///project/style.css")
+	handler.documents.open(uri, newDocument("css", 1, ".a { width: 144px; } .b { width: 72px; }"))
+
+	execute := func(arguments ...interface{}) error {
+		_, err := handler.ExecuteCommand(context.Background(), &protocol.ExecuteCommandParams{
+			Command:   commandConvertBlock,
+			Arguments: arguments,
+		})
+		return err
+	}
+
+	// arguments arrive decoded from json
+	if err := execute(string(uri), map[string]interface{}{"line": 0, "character": 21}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && len(conn.sent(protocol.MethodWorkspaceApplyEdit)) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	sent := conn.sent(protocol.MethodWorkspaceApplyEdit)
+	if len(sent) != 1 {
+		t.Fatalf("Expected 1 applyEdit, got %d", len(sent))
+	}
+	edits := sent[0].params.(*protocol.ApplyWorkspaceEditParams).Edit.Changes[uri]
+	expected := protocol.TextEdit{
+		Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 33}, End: protocol.Position{Line: 0, Character: 37}},
+		NewText: "5.000vw",
+	}
+	if len(edits) != 1 || edits[0] != expected {
+		t.Errorf("Expected only .b converted, got %v", edits)
+	}
+
+	tests := []struct {
+		name      string
+		arguments []interface{}
+	}{
+		{"No block at the position", []interface{}{uri, protocol.Position{Line: 0, Character: 3}}},
+		{"Missing position", []interface{}{uri}},
+		{"Closed document", []interface{}{"file:///project/other.css", protocol.Position{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := errorCode(execute(tt.arguments...)); code != jsonrpc2.InvalidParams {
+				t.Errorf("Expected InvalidParams, got %v", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+const commandConvertWorkspace = "pxToVw.convertWorkspace"
+
+func (h *Handler) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
+	log.Sugar().Infof("executeCommand: %s", params.Command)
+
+	switch params.Command {
+	case commandConvertWorkspace:
+		// walking the workspace can take a while, so the conversion runs in
+		// the background on a snapshot of the folders and open documents,
+		// reporting progress the client can cancel, until shutdown
+		go h.convertWorkspace(h.ctx, params.WorkDoneToken, h.workspaceTargets(), h.documents.snapshot(), h.getPositionEncoding())
+		return nil, nil
+	case commandConvertBlock:
+		var uri protocol.DocumentURI
+		var pos protocol.Position
+		if err := decodeArguments(params.Arguments, &uri, &pos); err != nil {
+			return nil, errInvalidParams("%s: %v", params.Command, err)
+		}
+		edits, err := h.blockEdits(uri, pos)
+		if err != nil {
+			return nil, err
+		}
+		if len(edits) == 0 {
+			return nil, nil
+		}
+		// the edit is applied in the background rather than holding up the reply
+		go func() {
+			edit := protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: edits}}
+			if applied, err := h.applyEdit(h.ctx, "Convert px → vw in block", edit); err != nil || !applied {
+				log.Sugar().Warnf("Block edit not applied: %v", err)
+			}
+		}()
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown command: %s", params.Command)
+	}
+}
+
+// decodeArguments decodes the arguments of a command into targets, one
+// argument each
+func decodeArguments(arguments []interface{}, targets ...interface{}) error {
+	if len(arguments) != len(targets) {
+		return fmt.Errorf("expected %d arguments, got %d", len(targets), len(arguments))
+	}
+	for i, argument := range arguments {
+		data, err := json.Marshal(argument)
+		if err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+		if err := json.Unmarshal(data, targets[i]); err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// workspaceTarget is a workspace folder and its effective config
+type workspaceTarget struct {
+	path   string
+	config *Config
+}
+
+// workspaceTargets returns the workspace folders, or the configured roots
+// when the client initialized with a rootUri only
+func (h *Handler) workspaceTargets() []workspaceTarget {
+	h.configsMu.RLock()
+	workspaceFolders := append([]protocol.WorkspaceFolder(nil), h.workspaceFolders...)
+	var targets []workspaceTarget
+	if len(workspaceFolders) == 0 {
+		for folderPath, config := range h.configs {
+			targets = append(targets, workspaceTarget{path: folderPath, config: config})
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i].path < targets[j].path })
+	}
+	h.configsMu.RUnlock()
+
+	for _, folder := range workspaceFolders {
+		targets = append(targets, workspaceTarget{
+			path:   strings.TrimPrefix(folder.URI, "file://"),
+			config: h.lookupConfig(protocol.DocumentURI(folder.URI)),
+		})
+	}
+	return targets
+}
+
+// convertWorkspace converts every px in the workspace folders and applies the
+// result as a single workspace edit
+func (h *Handler) convertWorkspace(ctx context.Context, token *protocol.ProgressToken, folders []workspaceTarget, documents map[protocol.DocumentURI]*document, encoding positionEncoding) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	progress := h.startProgress(ctx, token, "Converting px → vw", cancel)
+
+	edit, count, err := workspaceEdit(ctx, folders, documents, encoding, progress.report)
+	if errors.Is(err, context.Canceled) {
+		log.Sugar().Info("Workspace conversion cancelled")
+		progress.end(context.WithoutCancel(ctx), "Cancelled")
+		return
+	}
+	if err != nil {
+		log.Sugar().Warnf("Workspace conversion failed: %v", err)
+		progress.end(ctx, "Failed: "+err.Error())
+		return
+	}
+	if len(edit.Changes) == 0 {
+		progress.end(ctx, "No px values found")
+		return
+	}
+
+	applied, err := h.applyEdit(ctx, "Convert px → vw in workspace", edit)
+	if err != nil || !applied {
+		log.Sugar().Warnf("Workspace edit not applied: %v", err)
+		progress.end(ctx, "Edit was not applied")
+		return
+	}
+
+	log.Sugar().Infof("Converted %d px values in %d files", count, len(edit.Changes))
+	progress.end(ctx, fmt.Sprintf("Converted %d values in %d files", count, len(edit.Changes)))
+}
+
+// workspaceEdit builds the edit converting every matching file in folders,
+// preferring the open buffer in documents over the file on disk
+func workspaceEdit(ctx context.Context, folders []workspaceTarget, documents map[protocol.DocumentURI]*document, encoding positionEncoding, report func(done, total int)) (protocol.WorkspaceEdit, int, error) {
+	type folderFiles struct {
+		config *Config
+		files  []string
+	}
+
+	var all []folderFiles
+	total := 0
+	for _, folder := range folders {
+		files, err := collectFiles(ctx, folder.path, folder.config)
+		if err != nil {
+			return protocol.WorkspaceEdit{}, 0, fmt.Errorf("walk %s: %w", folder.path, err)
+		}
+		all = append(all, folderFiles{config: folder.config, files: files})
+		total += len(files)
+	}
+
+	// open documents are found whichever spelling of their uri the client uses
+	open := make(map[protocol.DocumentURI]protocol.DocumentURI, len(documents))
+	for openURI := range documents {
+		open[documentKey(openURI)] = openURI
+	}
+
+	edit := protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}
+	count, done := 0, 0
+	for _, folder := range all {
+		for _, path := range folder.files {
+			if err := ctx.Err(); err != nil {
+				return protocol.WorkspaceEdit{}, 0, err
+			}
+
+			fileURI, ok := open[documentKey(uri.File(path))]
+			doc := documents[fileURI]
+			if !ok {
+				fileURI = uri.File(path)
+				data, err := os.ReadFile(path)
+				if err != nil {
+					log.Sugar().Warnf("Skipping unreadable file %s: %v", path, err)
+					continue
+				}
+				doc = newDocument(protocol.LanguageIdentifier(languageForPath(path)), 0, string(data))
+			}
+
+			if edits := pxToVwEdits(doc, nil, folder.config, encoding); len(edits) > 0 {
+				edit.Changes[fileURI] = edits
+				count += len(edits)
+			}
+
+			done++
+			report(done, total)
+		}
+	}
+	return edit, count, nil
+}
+
+// applyEdit sends workspace/applyEdit. protocol.Client.ApplyEdit decodes the
+// response as a bool rather than ApplyWorkspaceEditResponse, so it can't be used.
+func (h *Handler) applyEdit(ctx context.Context, label string, edit protocol.WorkspaceEdit) (bool, error) {
+	if h.conn == nil {
+		return false, fmt.Errorf("no client connection")
+	}
+	var result protocol.ApplyWorkspaceEditResponse
+	params := &protocol.ApplyWorkspaceEditParams{Label: label, Edit: edit}
+	if err := protocol.Call(ctx, h.conn, protocol.MethodWorkspaceApplyEdit, params, &result); err != nil {
+		return false, err
+	}
+	if !result.Applied {
+		log.Sugar().Warnf("Client rejected workspace edit: %s", result.FailureReason)
+	}
+	return result.Applied, nil
+}
+
+// workDoneProgress reports $/progress for a token, doing nothing without a client
+type workDoneProgress struct {
+	client   protocol.Client
+	token    *protocol.ProgressToken
+	percent  uint32
+	reported time.Time
+	// done forgets the cancel func of the progress
+	done func()
+}
+
+// startProgress begins work done progress on token, creating one when the
+// client didn't supply it. A non-nil cancel makes the progress cancellable
+// with window/workDoneProgress/cancel.
+func (h *Handler) startProgress(ctx context.Context, token *protocol.ProgressToken, title string, cancel context.CancelFunc) *workDoneProgress {
+	if h.client == nil {
+		return &workDoneProgress{}
+	}
+
+	if token == nil {
+		token = protocol.NewProgressToken(fmt.Sprintf("px-to-vw-lsp/%d", time.Now().UnixNano()))
+		if err := h.client.WorkDoneProgressCreate(ctx, &protocol.WorkDoneProgressCreateParams{Token: *token}); err != nil {
+			log.Sugar().Debugf("Client refused work done progress: %v", err)
+			return &workDoneProgress{}
+		}
+	}
+
+	p := &workDoneProgress{client: h.client, token: token, done: func() {}}
+	if cancel != nil {
+		key := token.String()
+		h.stateMu.Lock()
+		h.progressCancels[key] = cancel
+		h.stateMu.Unlock()
+		p.done = func() {
+			h.stateMu.Lock()
+			delete(h.progressCancels, key)
+			h.stateMu.Unlock()
+		}
+	}
+
+	p.send(ctx, &protocol.WorkDoneProgressBegin{
+		Kind:        protocol.WorkDoneProgressKindBegin,
+		Title:       title,
+		Cancellable: cancel != nil,
+	})
+	return p
+}
+
+// report sends a progress report, throttled to percentage changes
+func (p *workDoneProgress) report(done, total int) {
+	if p.client == nil || total == 0 {
+		return
+	}
+	percent := uint32(done * 100 / total)
+	if percent == p.percent && time.Since(p.reported) < time.Second {
+		return
+	}
+	p.percent = percent
+	p.reported = time.Now()
+	p.send(context.Background(), &protocol.WorkDoneProgressReport{
+		Kind:       protocol.WorkDoneProgressKindReport,
+		Message:    fmt.Sprintf("%d/%d files", done, total),
+		Percentage: percent,
+	})
+}
+
+func (p *workDoneProgress) end(ctx context.Context, message string) {
+	if p.client == nil {
+		return
+	}
+	p.done()
+	p.send(ctx, &protocol.WorkDoneProgressEnd{
+		Kind:    protocol.WorkDoneProgressKindEnd,
+		Message: message,
+	})
+}
+
+func (p *workDoneProgress) send(ctx context.Context, value interface{}) {
+	if err := p.client.Progress(ctx, &protocol.ProgressParams{Token: *p.token, Value: value}); err != nil {
+		log.Sugar().Debugf("Failed to send progress: %v", err)
+	}
+}
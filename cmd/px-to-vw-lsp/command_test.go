@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestWorkspaceEdit(t *testing.T) {
+	log = createTestLogger(t)
+	root := t.TempDir()
+	files := map[string]string{
+		"a.css":   ".a {\n  width: 144px;\n  border: 1px solid;\n}",
+		"b.css":   ".b { color: red; }",
+		"c.scss":  ".c { margin: 72px; }",
+		"skip.js": "const w = '10px'",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	config := &Config{ViewportWidth: 1440, UnitPrecision: 3, IgnoresViaCommand: []string{"1px"}}
+	openURI := protocol.DocumentURI("file://" + filepath.Join(root, "c.scss"))
+	documents := map[protocol.DocumentURI]*document{
+		openURI: newDocument("scss", 1, ".c { margin: 72px; padding: 36px; }"),
+	}
+
+	var reports int
+	edit, count, err := workspaceEdit(context.Background(),
+		[]workspaceTarget{{path: root, config: config}}, documents, positionEncodingUTF16,
+		func(done, total int) { reports++ })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if count != 3 {
+		t.Errorf("Converted count: got %d, want 3", count)
+	}
+	if len(edit.Changes) != 2 {
+		t.Errorf("Changed files: got %d, want 2", len(edit.Changes))
+	}
+	if reports != 3 {
+		t.Errorf("Progress reports: got %d, want 3", reports)
+	}
+
+	aEdits := edit.Changes[protocol.DocumentURI("file://"+filepath.Join(root, "a.css"))]
+	if len(aEdits) != 1 || aEdits[0].NewText != "10.000vw" {
+		t.Errorf("a.css edits: got %v", aEdits)
+	}
+	if len(edit.Changes[openURI]) != 2 {
+		t.Errorf("Open document should use buffer contents, got %v", edit.Changes[openURI])
+	}
+}
+
+func TestWorkspaceTargetsRootURI(t *testing.T) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	config := &Config{ViewportWidth: 1920}
+	handler.configs["/project"] = config
+
+	// a client initialized with rootUri only has no workspace folders
+	targets := handler.workspaceTargets()
+	if len(targets) != 1 || targets[0].path != "/project" || targets[0].config != config {
+		t.Errorf("Expected the configured root, got %+v", targets)
+	}
+}
+
+func TestWorkspaceEditEncodedURI(t *testing.T) {
+	log = createTestLogger(t)
+	root := filepath.Join(t.TempDir(), "my project")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	path := filepath.Join(root, "a.css")
+	if err := os.WriteFile(path, []byte(".a { width: 144px; }"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// the client spells the uri of the open buffer with its own escapes
+	openURI := protocol.DocumentURI("file://" + strings.ReplaceAll(filepath.ToSlash(path), "a.css", "%61.css"))
+	documents := map[protocol.DocumentURI]*document{
+		openURI: newDocument("css", 2, ".a { width: 72px; }"),
+	}
+	config := &Config{ViewportWidth: 1440, UnitPrecision: 3}
+	edit, _, err := workspaceEdit(context.Background(),
+		[]workspaceTarget{{path: root, config: config}}, documents, positionEncodingUTF16,
+		func(done, total int) {})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	edits := edit.Changes[openURI]
+	if len(edit.Changes) != 1 || len(edits) != 1 || edits[0].NewText != "5.000vw" {
+		t.Errorf("Expected the open buffer converted, got %v", edit.Changes)
+	}
+}
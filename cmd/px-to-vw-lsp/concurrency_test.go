@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// TestConcurrentRequests hammers the handler from several goroutines, run
+// it with -race to catch unguarded state
+func TestConcurrentRequests(t *testing.T) {
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, VwHover: true, Diagnostics: SchemaJsonDiagnosticsWarning}
+	handler, uri := newTestHandler(t, ".a {\n  width: 348px;\n}", config)
+	ctx := context.Background()
+
+	const workers, iterations = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(4)
+
+		// open, edit and close documents of its own
+		go func() {
+			defer wg.Done()
+			own := protocol.DocumentURI(fmt.Sprintf("file:///project/own%d.css", w))
+			for i := 0; i < iterations; i++ {
+				handler.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{TextDocument: protocol.TextDocumentItem{
+					URI: own, LanguageID: "css", Version: 1, Text: ".b { margin: 10px; }",
+				}})
+				handler.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+					TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: own}, Version: 2},
+					ContentChanges: []protocol.TextDocumentContentChangeEvent{{Range: *rangeOf(0, 0, 0, 0), Text: "/* x */\n"}},
+				})
+				handler.DidClose(ctx, &protocol.DidCloseTextDocumentParams{TextDocument: protocol.TextDocumentIdentifier{URI: own}})
+			}
+		}()
+
+		// grow the shared document by a line at a time
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				err := handler.didChange(ctx, &didChangeParams{
+					TextDocument:   protocol.VersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri}, Version: int32(i)},
+					ContentChanges: []contentChange{{Range: rangeOf(0, 0, 0, 0), Text: "  top: 10px;\n"}},
+				})
+				if err != nil {
+					t.Errorf("didChange: %v", err)
+				}
+			}
+		}()
+
+		// query the shared document
+		go func() {
+			defer wg.Done()
+			position := protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     protocol.Position{Line: 0, Character: 11},
+			}
+			for i := 0; i < iterations; i++ {
+				if _, err := handler.Completion(ctx, &protocol.CompletionParams{TextDocumentPositionParams: position}); err != nil && !errors.Is(err, errContentModified) {
+					t.Errorf("Completion: %v", err)
+				}
+				if _, err := handler.Hover(ctx, &protocol.HoverParams{TextDocumentPositionParams: position}); err != nil && !errors.Is(err, errContentModified) {
+					t.Errorf("Hover: %v", err)
+				}
+				if _, err := handler.CodeAction(ctx, &protocol.CodeActionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+					Range:        *rangeOf(0, 0, 1, 0),
+				}); err != nil && !errors.Is(err, errContentModified) {
+					t.Errorf("CodeAction: %v", err)
+				}
+			}
+		}()
+
+		// add and remove workspace folders while the configs are read
+		go func() {
+			defer wg.Done()
+			folder := protocol.WorkspaceFolder{URI: fmt.Sprintf("file://%s", t.TempDir()), Name: "tmp"}
+			for i := 0; i < iterations; i++ {
+				handler.DidChangeWorkspaceFolders(ctx, &protocol.DidChangeWorkspaceFoldersParams{
+					Event: protocol.WorkspaceFoldersChangeEvent{Added: []protocol.WorkspaceFolder{folder}},
+				})
+				handler.reloadAllConfigs()
+				handler.DidChangeWorkspaceFolders(ctx, &protocol.DidChangeWorkspaceFoldersParams{
+					Event: protocol.WorkspaceFoldersChangeEvent{Removed: []protocol.WorkspaceFolder{folder}},
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	handler.Close()
+
+	doc, ok := handler.documents.get(uri)
+	if !ok {
+		t.Fatal("Shared document was dropped")
+	}
+	if expected := 3 + workers*iterations; len(doc.Lines()) != expected {
+		t.Errorf("Expected %d lines, got %d", expected, len(doc.Lines()))
+	}
+	if len(handler.documents.snapshot()) != 1 {
+		t.Errorf("Expected only the shared document to stay open, got %d", len(handler.documents.snapshot()))
+	}
+}
+
+func TestConcurrentHandler(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan string, 2)
+	h := concurrentHandler(func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if _, ok := req.(*jsonrpc2.Call); ok {
+			<-release
+		}
+		handled <- req.Method()
+		return reply(ctx, nil, nil)
+	})
+	noReply := func(ctx context.Context, result interface{}, err error) error { return nil }
+
+	call, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodTextDocumentCompletion, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notification, err := jsonrpc2.NewNotification(protocol.MethodTextDocumentDidChange, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocked call must not hold up the notification behind it
+	if err := h(context.Background(), noReply, call); err != nil {
+		t.Fatal(err)
+	}
+	if err := h(context.Background(), noReply, notification); err != nil {
+		t.Fatal(err)
+	}
+	if method := <-handled; method != protocol.MethodTextDocumentDidChange {
+		t.Errorf("Expected the notification to be handled first, got %s", method)
+	}
+
+	close(release)
+	if method := <-handled; method != protocol.MethodTextDocumentCompletion {
+		t.Errorf("Expected the call to be handled, got %s", method)
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
 
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
 	"go.uber.org/zap"
 )
 
@@ -14,6 +14,84 @@ import (
 type Config struct {
 	ViewportWidth float64 `json:"viewportWidth"`
 	UnitPrecision int     `json:"unitPrecision"`
+
+	// Breakpoints override ViewportWidth inside matching @media blocks
+	Breakpoints []convert.Breakpoint `json:"breakpoints"`
+
+	// MinViewportWidth is the viewport width clamp() sizes start growing at
+	MinViewportWidth float64 `json:"minViewportWidth"`
+
+	// ConvertConditions converts values in @media, @container and @supports conditions too
+	ConvertConditions bool `json:"convertConditions"`
+
+	// RootFontSize is the root font-size in px used for rem conversion
+	RootFontSize float64 `json:"rootFontSize"`
+
+	// hover options, mirroring the cssrem `hover`, `vwHover`, `remHover` and `addMark` settings
+	Hover    SchemaJsonHover `json:"hover"`
+	VwHover  bool            `json:"vwHover"`
+	RemHover bool            `json:"remHover"`
+	AddMark  bool            `json:"addMark"`
+
+	// Vw enables vw to px conversion, like the cssrem `vw` switch
+	Vw bool `json:"vw"`
+
+	// CurrentLine limits inlay hints to the cursor line when "show"
+	CurrentLine SchemaJsonCurrentLine `json:"currentLine"`
+
+	// IgnoresViaCommand lists values left alone by code actions, e.g. ["1px"]
+	IgnoresViaCommand []string `json:"ignoresViaCommand"`
+
+	// Ignores are globs of files skipped by workspace conversion,
+	// Languages the language ids it converts
+	Ignores   []string `json:"ignores"`
+	Languages []string `json:"languages"`
+
+	// Diagnostics is the severity raw px values are reported with,
+	// AllowedPxProperties the properties where px is fine, e.g. "border-width"
+	Diagnostics         SchemaJsonDiagnostics `json:"diagnostics"`
+	AllowedPxProperties []string              `json:"allowedPxProperties"`
+
+	// wxss options for px <-> rpx conversion in WeChat mini-programs
+	Wxss            bool    `json:"wxss"`
+	WxssDeviceWidth float64 `json:"wxssDeviceWidth"`
+	WxssScreenWidth float64 `json:"wxssScreenWidth"`
+
+	// Source is the path of the config file the values came from, empty for defaults
+	Source string `json:"-"`
+}
+
+// converter returns the converter for the conversion settings of the config
+func (c *Config) converter() *convert.Converter {
+	return c.converterFor("")
+}
+
+// converterFor returns the converter for texts of a language, e.g. "css",
+// which decides whether `//` starts a comment
+func (c *Config) converterFor(language string) *convert.Converter {
+	converter, err := convert.New(convert.Config{
+		ViewportWidth:     c.ViewportWidth,
+		UnitPrecision:     c.UnitPrecision,
+		RootFontSize:      c.RootFontSize,
+		Vw:                c.Vw,
+		Wxss:              c.Wxss,
+		WxssDeviceWidth:   c.WxssDeviceWidth,
+		WxssScreenWidth:   c.WxssScreenWidth,
+		AddMark:           c.AddMark,
+		IgnoresViaCommand: c.IgnoresViaCommand,
+		Breakpoints:       c.Breakpoints,
+		ConvertConditions: c.ConvertConditions,
+		MinViewportWidth:  c.MinViewportWidth,
+		Dialect:           convert.DialectFor(language),
+	})
+	if err != nil {
+		// mergeConfigs only takes a positive viewport width and precision
+		// from the config files, so only a config built by hand gets here
+		log.Sugar().Warnf("Invalid config, converting with the defaults: %v", err)
+		defaults := loadDefaultConfig()
+		return defaults.converterFor(language)
+	}
+	return converter
 }
 
 // TODO clean up vibe coded code
@@ -23,47 +101,49 @@ type GlobalConfig struct {
 	configPath string
 	mu         sync.RWMutex
 	watcher    *fileWatcher
-}
-
-// fileWatcher monitors file changes
-type fileWatcher struct {
-	path    string
-	watcher chan struct{}
-	ctx     context.Context
-	cancel  context.CancelFunc
+	listeners  []func()
 }
 
 // NewGlobalConfig creates a new global config manager
 func NewGlobalConfig(ctx context.Context, logger *zap.Logger) (*GlobalConfig, error) {
+	globalConfig := loadGlobalConfig(logger)
+
+	// Start file monitoring
+	if err := globalConfig.startWatcher(ctx, logger); err != nil {
+		logger.Sugar().Warnf("Failed to start global config watcher: %v", err)
+	}
+
+	return globalConfig, nil
+}
+
+// loadGlobalConfig reads the global config once, without watching it for changes
+func loadGlobalConfig(logger *zap.Logger) *GlobalConfig {
 	sugar := logger.Sugar()
 
 	userConfigDir, err := os.UserConfigDir()
 	if err != nil {
 		sugar.Warnf("Failed to get user config dir: %v", err)
+		defaultConfig := loadDefaultConfig()
 		return &GlobalConfig{
-			config:     &Config{ViewportWidth: 1440, UnitPrecision: 3},
+			config:     &defaultConfig,
 			configPath: "",
-		}, nil
+		}
 	}
 
 	configPath := filepath.Join(userConfigDir, "px-to-vw-lsp", "config.json")
 
+	defaultConfig := loadDefaultConfig()
 	globalConfig := &GlobalConfig{
-		config:     &Config{ViewportWidth: 1440, UnitPrecision: 3},
+		config:     &defaultConfig,
 		configPath: configPath,
 	}
 
 	// Load global config if it exists
-	if err := globalConfig.load(logger); err != nil {
+	if err := globalConfig.load(logger); err != nil && !os.IsNotExist(err) {
 		sugar.Warnf("Failed to load global config: %v", err)
 	}
 
-	// Start file monitoring
-	if err := globalConfig.startWatcher(ctx, logger); err != nil {
-		sugar.Warnf("Failed to start global config watcher: %v", err)
-	}
-
-	return globalConfig, nil
+	return globalConfig
 }
 
 // Get returns the current global config
@@ -73,6 +153,18 @@ func (g *GlobalConfig) Get() *Config {
 	return g.config
 }
 
+// Path returns the path of the global config file, empty if there is none
+func (g *GlobalConfig) Path() string {
+	return g.configPath
+}
+
+// OnChange registers fn to be called after the global config is reloaded
+func (g *GlobalConfig) OnChange(fn func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.listeners = append(g.listeners, fn)
+}
+
 // load reads and parses the global config file
 func (g *GlobalConfig) load(logger *zap.Logger) error {
 	if g.configPath == "" {
@@ -90,6 +182,7 @@ func (g *GlobalConfig) load(logger *zap.Logger) error {
 	}
 
 	config := convertToConfig(*cssremConfig)
+	config.Source = g.configPath
 
 	g.mu.Lock()
 	g.config = &config
@@ -101,78 +194,54 @@ func (g *GlobalConfig) load(logger *zap.Logger) error {
 	return nil
 }
 
+// Reload re-reads the global config after a change on disk and notifies listeners.
+// A deleted file resets the config to defaults, an unparsable one keeps the last good config.
+func (g *GlobalConfig) Reload(logger *zap.Logger) {
+	if err := g.load(logger); err != nil {
+		if !os.IsNotExist(err) {
+			logger.Sugar().Warnf("Failed to reload global config: %v", err)
+			return
+		}
+		defaultConfig := loadDefaultConfig()
+		g.mu.Lock()
+		g.config = &defaultConfig
+		g.mu.Unlock()
+		logger.Sugar().Infof("Global config %s removed, using defaults", g.configPath)
+	}
+
+	g.mu.RLock()
+	listeners := append([]func(){}, g.listeners...)
+	g.mu.RUnlock()
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
 // startWatcher monitors the global config file for changes
 func (g *GlobalConfig) startWatcher(ctx context.Context, logger *zap.Logger) error {
 	if g.configPath == "" {
 		return nil
 	}
 
-	// Create a dedicated context for file watching with cancellation
-	watcherCtx, cancel := context.WithCancel(ctx)
-
-	g.watcher = &fileWatcher{
-		path:   g.configPath,
-		ctx:    watcherCtx,
-		cancel: cancel,
-	}
-
 	// Ensure config directory exists
 	if err := os.MkdirAll(filepath.Dir(g.configPath), 0755); err != nil {
 		logger.Sugar().Warnf("Failed to create global config directory: %v", err)
 		return nil
 	}
 
-	// Start file monitoring in a goroutine
-	go g.monitorFile(logger)
+	watcher := newFileWatcher(ctx, g.configPath, logger, func() { g.Reload(logger) })
+	g.mu.Lock()
+	g.watcher = watcher
+	g.mu.Unlock()
 	return nil
 }
 
-// monitorFile watches for file changes using stat polling
-func (g *GlobalConfig) monitorFile(logger *zap.Logger) {
-	if g.watcher == nil {
-		return
-	}
-
-	var lastModTime time.Time
-	ticker := time.NewTicker(2 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-g.watcher.ctx.Done():
-			return
-		case <-ticker.C:
-			if g.watchFile(&lastModTime) {
-				if err := g.load(logger); err != nil {
-					logger.Sugar().Warnf("Failed to reload global config: %v", err)
-				}
-			}
-		}
-	}
-}
-
-// watchFile checks if the config file has been modified
-func (g *GlobalConfig) watchFile(lastModTime *time.Time) bool {
-	info, err := os.Stat(g.configPath)
-	if err != nil {
-		return false
-	}
-
-	if info.ModTime().After(*lastModTime) {
-		*lastModTime = info.ModTime()
-		return true
-	}
-
-	return false
-}
-
 // Close stops the file watcher
 func (g *GlobalConfig) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if g.watcher != nil {
-		// Cancel the watcher's context to stop the goroutine
-		if g.watcher.cancel != nil {
-			g.watcher.cancel()
-		}
+		g.watcher.Close()
 		// Clear the watcher
 		g.watcher = nil
 	}
@@ -180,34 +249,49 @@ func (g *GlobalConfig) Close() {
 
 func loadDefaultConfig() Config {
 	return Config{
-		ViewportWidth: 1440,
-		UnitPrecision: 3,
+		ViewportWidth:   1440,
+		UnitPrecision:   3,
+		RootFontSize:    16,
+		Hover:           SchemaJsonHoverAlways,
+		CurrentLine:     SchemaJsonCurrentLineShow,
+		VwHover:         true,
+		RemHover:        true,
+		WxssDeviceWidth: 375,
+		WxssScreenWidth: 750,
+		Diagnostics:     SchemaJsonDiagnosticsOff,
+
+		MinViewportWidth: 375,
 	}
 }
 
-func loadConfig(root string, logger *zap.Logger) Config {
+// readProjectConfig reads the .cssrem in root, reporting false when it is missing or invalid
+func readProjectConfig(root string, logger *zap.Logger) (Config, bool) {
 	sugar := logger.Sugar()
-	defaultConfig := loadDefaultConfig()
 
 	cssremPath := filepath.Join(root, ".cssrem")
 	file, err := os.ReadFile(cssremPath)
 	if err != nil {
-		sugar.Warnf("Failed to open config file %s: %v", cssremPath, err)
-		return defaultConfig
+		if os.IsNotExist(err) {
+			sugar.Debugf("No config file at %s", cssremPath)
+		} else {
+			sugar.Warnf("Failed to open config file %s: %v", cssremPath, err)
+		}
+		return Config{}, false
 	}
 
 	cssremConfig, err := parseCssremConfig(file)
 	if err != nil {
 		sugar.Warnf("Failed to parse config file %s: %v", cssremPath, err)
-		return defaultConfig
+		return Config{}, false
 	}
 
 	config := convertToConfig(*cssremConfig)
+	config.Source = cssremPath
 
 	sugar.Infof("Loaded config from %s: viewport=%.0f, precision=%d",
 		cssremPath, config.ViewportWidth, config.UnitPrecision)
 
-	return config
+	return config, true
 }
 
 func parseCssremConfig(data []byte) (*SchemaJson, error) {
@@ -224,26 +308,83 @@ func mergeConfigs(defaultConfig, globalConfig, projectConfig Config) Config {
 	result := defaultConfig
 
 	// Global config overrides defaults
-	if globalConfig.ViewportWidth != 0 {
+	if globalConfig.ViewportWidth > 0 {
 		result.ViewportWidth = globalConfig.ViewportWidth
 	}
-	if globalConfig.UnitPrecision != 0 {
+	if globalConfig.UnitPrecision > 0 {
 		result.UnitPrecision = globalConfig.UnitPrecision
 	}
+	if globalConfig.MinViewportWidth != 0 {
+		result.MinViewportWidth = globalConfig.MinViewportWidth
+	}
+	if globalConfig.RootFontSize != 0 {
+		result.RootFontSize = globalConfig.RootFontSize
+	}
+	if globalConfig.WxssDeviceWidth != 0 {
+		result.WxssDeviceWidth = globalConfig.WxssDeviceWidth
+	}
+	if globalConfig.WxssScreenWidth != 0 {
+		result.WxssScreenWidth = globalConfig.WxssScreenWidth
+	}
+	// Non-numeric options can't be told apart from their zero values,
+	// so they are only taken from layers that were read from a file
+	if globalConfig.Source != "" {
+		mergeFileOptions(&result, globalConfig)
+	}
 
 	// Project config overrides global and defaults
-	if projectConfig.ViewportWidth != 0 {
+	if projectConfig.ViewportWidth > 0 {
 		result.ViewportWidth = projectConfig.ViewportWidth
 	}
-	if projectConfig.UnitPrecision != 0 {
+	if projectConfig.UnitPrecision > 0 {
 		result.UnitPrecision = projectConfig.UnitPrecision
 	}
+	if projectConfig.MinViewportWidth != 0 {
+		result.MinViewportWidth = projectConfig.MinViewportWidth
+	}
+	if projectConfig.RootFontSize != 0 {
+		result.RootFontSize = projectConfig.RootFontSize
+	}
+	if projectConfig.WxssDeviceWidth != 0 {
+		result.WxssDeviceWidth = projectConfig.WxssDeviceWidth
+	}
+	if projectConfig.WxssScreenWidth != 0 {
+		result.WxssScreenWidth = projectConfig.WxssScreenWidth
+	}
+	if projectConfig.Source != "" {
+		mergeFileOptions(&result, projectConfig)
+	}
 
 	return result
 }
 
+// mergeFileOptions copies the options of a config loaded from a file into result
+func mergeFileOptions(result *Config, layer Config) {
+	if layer.Hover != "" {
+		result.Hover = layer.Hover
+	}
+	if layer.CurrentLine != "" {
+		result.CurrentLine = layer.CurrentLine
+	}
+	result.VwHover = layer.VwHover
+	result.RemHover = layer.RemHover
+	result.AddMark = layer.AddMark
+	result.Vw = layer.Vw
+	result.Wxss = layer.Wxss
+	result.IgnoresViaCommand = layer.IgnoresViaCommand
+	result.Breakpoints = layer.Breakpoints
+	result.ConvertConditions = layer.ConvertConditions
+	result.Ignores = layer.Ignores
+	result.Languages = layer.Languages
+	if layer.Diagnostics != "" {
+		result.Diagnostics = layer.Diagnostics
+	}
+	result.AllowedPxProperties = layer.AllowedPxProperties
+	result.Source = layer.Source
+}
+
 // loadEffectiveConfig loads the final config with priority: default < global < project
-func (h *Handler) loadEffectiveConfig(globalConfig *GlobalConfig, root string, logger *zap.Logger) Config {
+func loadEffectiveConfig(globalConfig *GlobalConfig, root string, logger *zap.Logger) Config {
 	defaultConfig := loadDefaultConfig()
 
 	var globalConfigValues Config
@@ -251,7 +392,8 @@ func (h *Handler) loadEffectiveConfig(globalConfig *GlobalConfig, root string, l
 		globalConfigValues = *globalConfig.Get()
 	}
 
-	projectConfig := loadConfig(root, logger)
+	// a missing .cssrem contributes nothing, so the global config still applies
+	projectConfig, _ := readProjectConfig(root, logger)
 
 	// Merge configs with priority: default < global < project
 	effectiveConfig := mergeConfigs(defaultConfig, globalConfigValues, projectConfig)
@@ -266,8 +408,132 @@ func (h *Handler) loadEffectiveConfig(globalConfig *GlobalConfig, root string, l
 }
 
 func convertToConfig(schema SchemaJson) Config {
+	var breakpoints []convert.Breakpoint
+	for _, breakpoint := range schema.Breakpoints {
+		breakpoints = append(breakpoints, convert.Breakpoint{Media: breakpoint.Media, ViewportWidth: breakpoint.VwDesign})
+	}
 	return Config{
-		ViewportWidth: schema.VwDesign,
-		UnitPrecision: int(schema.FixedDigits),
+		ViewportWidth:   schema.VwDesign,
+		UnitPrecision:   int(schema.FixedDigits),
+		RootFontSize:    schema.RootFontSize,
+		Hover:           schema.Hover,
+		CurrentLine:     schema.CurrentLine,
+		VwHover:         schema.VwHover,
+		RemHover:        schema.RemHover,
+		AddMark:         schema.AddMark,
+		Vw:              schema.Vw,
+		Wxss:            schema.Wxss,
+		WxssDeviceWidth: schema.WxssDeviceWidth,
+		WxssScreenWidth: schema.WxssScreenWidth,
+
+		IgnoresViaCommand: schema.IgnoresViaCommand,
+		Breakpoints:       breakpoints,
+		ConvertConditions: schema.ConvertConditions,
+		Ignores:           schema.Ignores,
+		Languages:         schema.Languages,
+
+		Diagnostics:         schema.Diagnostics,
+		AllowedPxProperties: schema.AllowedPxProperties,
+
+		MinViewportWidth: schema.MinVwDesign,
+	}
+}
+
+// addConfigFolder loads the effective config of a workspace folder and
+// watches its .cssrem so the config follows edits, creation and deletion
+func (h *Handler) addConfigFolder(folderPath string) {
+	config := loadEffectiveConfig(h.globalConfig, folderPath, log)
+	h.configsMu.Lock()
+	h.configs[folderPath] = &config
+	h.configsMu.Unlock()
+	log.Sugar().Infof("Loaded effective config for workspace folder: %s (viewport: %.0f, precision: %d)",
+		folderPath, config.ViewportWidth, config.UnitPrecision)
+
+	h.configsMu.RLock()
+	clientWatchesFiles := h.clientWatchesFiles
+	h.configsMu.RUnlock()
+	if !clientWatchesFiles {
+		h.watchConfigFolder(folderPath)
+	}
+}
+
+// watchConfigFolder watches a workspace folder's .cssrem on the server side,
+// for clients that can't send workspace/didChangeWatchedFiles
+func (h *Handler) watchConfigFolder(folderPath string) {
+	watcher := newFileWatcher(context.Background(), filepath.Join(folderPath, ".cssrem"), log, func() {
+		log.Sugar().Infof("Config file changed in workspace folder: %s", folderPath)
+		h.reloadConfigFolder(folderPath)
+	})
+
+	h.configsMu.Lock()
+	if old, ok := h.configWatchers[folderPath]; ok {
+		old.Close()
+	}
+	h.configWatchers[folderPath] = watcher
+	h.configsMu.Unlock()
+}
+
+// removeConfigFolder forgets a workspace folder's config and stops watching it
+func (h *Handler) removeConfigFolder(folderPath string) {
+	h.configsMu.Lock()
+	defer h.configsMu.Unlock()
+
+	delete(h.configs, folderPath)
+	if watcher, ok := h.configWatchers[folderPath]; ok {
+		watcher.Close()
+		delete(h.configWatchers, folderPath)
+	}
+}
+
+// reloadConfigFolder recomputes the effective config of a workspace folder
+// and republishes the diagnostics of its open documents, unless the folder
+// has been removed in the meantime
+func (h *Handler) reloadConfigFolder(folderPath string) {
+	if h.updateConfigFolder(folderPath) {
+		h.refreshDiagnostics(folderPath)
+	}
+}
+
+// updateConfigFolder re-merges the effective config of a workspace folder,
+// reporting whether the folder is still open
+func (h *Handler) updateConfigFolder(folderPath string) bool {
+	config := loadEffectiveConfig(h.globalConfig, folderPath, log)
+
+	h.configsMu.Lock()
+	defer h.configsMu.Unlock()
+	if _, ok := h.configs[folderPath]; !ok {
+		return false
+	}
+	h.configs[folderPath] = &config
+
+	log.Sugar().Infof("Reloaded effective config for workspace folder: %s (viewport: %.0f, precision: %d)",
+		folderPath, config.ViewportWidth, config.UnitPrecision)
+	return true
+}
+
+// reloadAllConfigs re-merges every workspace folder's config, e.g. after the global config changed
+func (h *Handler) reloadAllConfigs() {
+	h.configsMu.RLock()
+	folders := make([]string, 0, len(h.configs))
+	for folderPath := range h.configs {
+		folders = append(folders, folderPath)
+	}
+	h.configsMu.RUnlock()
+
+	for _, folderPath := range folders {
+		h.updateConfigFolder(folderPath)
+	}
+	// documents outside the folders use the global config itself
+	h.refreshDiagnostics("")
+}
+
+// Close stops watching the workspace config files
+func (h *Handler) Close() {
+	h.configsMu.Lock()
+	defer h.configsMu.Unlock()
+
+	for folderPath, watcher := range h.configWatchers {
+		watcher.Close()
+		delete(h.configWatchers, folderPath)
 	}
 }
@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func createTestLogger(t *testing.T) *zap.Logger {
@@ -134,6 +139,24 @@ func TestParseCssremConfig(t *testing.T) {
 	}
 }
 
+func TestParseCssremBreakpoints(t *testing.T) {
+	var schema SchemaJson
+	input := `{"vwDesign": 1440, "breakpoints": [{"media": "(max-width: 768px)", "vwDesign": 375}]}`
+	if err := json.Unmarshal([]byte(input), &schema); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := convertToConfig(schema)
+	expected := []convert.Breakpoint{{Media: "(max-width: 768px)", ViewportWidth: 375}}
+	if !reflect.DeepEqual(config.Breakpoints, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, config.Breakpoints)
+	}
+
+	// a breakpoint without a width is an error rather than a width of 0
+	if err := json.Unmarshal([]byte(`{"breakpoints": [{"media": "print"}]}`), &schema); err == nil {
+		t.Error("Expected an error for a breakpoint without vwDesign")
+	}
+}
+
 func TestConvertToConfig(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -173,6 +196,19 @@ func TestConvertToConfig(t *testing.T) {
 				UnitPrecision: 5,
 			},
 		},
+		{
+			name: "Root font size",
+			input: SchemaJson{
+				VwDesign:     1440,
+				FixedDigits:  3,
+				RootFontSize: 20,
+			},
+			expected: Config{
+				ViewportWidth: 1440,
+				UnitPrecision: 3,
+				RootFontSize:  20,
+			},
+		},
 		{
 			name: "Decimal viewport width",
 			input: SchemaJson{
@@ -196,11 +232,14 @@ func TestConvertToConfig(t *testing.T) {
 			if result.UnitPrecision != tt.expected.UnitPrecision {
 				t.Errorf("UnitPrecision: got %d, want %d", result.UnitPrecision, tt.expected.UnitPrecision)
 			}
+			if result.RootFontSize != tt.expected.RootFontSize {
+				t.Errorf("RootFontSize: got %f, want %f", result.RootFontSize, tt.expected.RootFontSize)
+			}
 		})
 	}
 }
 
-func TestLoadConfig(t *testing.T) {
+func TestReadProjectConfig(t *testing.T) {
 	// Test loading config from file
 	t.Run("Load from valid config file", func(t *testing.T) {
 		tempDir := t.TempDir()
@@ -216,8 +255,11 @@ func TestLoadConfig(t *testing.T) {
 			t.Fatalf("Failed to write test config file: %v", err)
 		}
 
-		config := loadConfig(tempDir, logger)
+		config, ok := readProjectConfig(tempDir, logger)
 
+		if !ok {
+			t.Fatal("Expected the config file to be read")
+		}
 		if config.ViewportWidth != 1920 {
 			t.Errorf("ViewportWidth: got %f, want 1920", config.ViewportWidth)
 		}
@@ -230,15 +272,14 @@ func TestLoadConfig(t *testing.T) {
 	t.Run("Load when config file doesn't exist", func(t *testing.T) {
 		tempDir := t.TempDir()
 		logger := createTestLogger(t)
-		config := loadConfig(tempDir, logger)
+		config, ok := readProjectConfig(tempDir, logger)
 
-		// Should return default config
-		defaultConfig := loadDefaultConfig()
-		if config.ViewportWidth != defaultConfig.ViewportWidth {
-			t.Errorf("ViewportWidth: got %f, want %f", config.ViewportWidth, defaultConfig.ViewportWidth)
+		// Should contribute nothing, leaving the defaults to the merge
+		if ok {
+			t.Error("Expected a missing config file to be reported")
 		}
-		if config.UnitPrecision != defaultConfig.UnitPrecision {
-			t.Errorf("UnitPrecision: got %d, want %d", config.UnitPrecision, defaultConfig.UnitPrecision)
+		if config.ViewportWidth != 0 || config.UnitPrecision != 0 {
+			t.Errorf("Expected an empty config, got viewport %f, precision %d", config.ViewportWidth, config.UnitPrecision)
 		}
 	})
 
@@ -254,15 +295,14 @@ func TestLoadConfig(t *testing.T) {
 			t.Fatalf("Failed to write test config file: %v", err)
 		}
 
-		config := loadConfig(tempDir, logger)
+		config, ok := readProjectConfig(tempDir, logger)
 
-		// Should return default config on error
-		defaultConfig := loadDefaultConfig()
-		if config.ViewportWidth != defaultConfig.ViewportWidth {
-			t.Errorf("ViewportWidth: got %f, want %f", config.ViewportWidth, defaultConfig.ViewportWidth)
+		// Should contribute nothing on error
+		if ok {
+			t.Error("Expected an invalid config file to be reported")
 		}
-		if config.UnitPrecision != defaultConfig.UnitPrecision {
-			t.Errorf("UnitPrecision: got %d, want %d", config.UnitPrecision, defaultConfig.UnitPrecision)
+		if config.ViewportWidth != 0 || config.UnitPrecision != 0 {
+			t.Errorf("Expected an empty config, got viewport %f, precision %d", config.ViewportWidth, config.UnitPrecision)
 		}
 	})
 }
@@ -415,13 +455,8 @@ func TestHandlerLoadEffectiveConfig(t *testing.T) {
 	}
 	defer globalConfig.Close()
 
-	// Create handler
-	handler := &Handler{
-		globalConfig: globalConfig,
-	}
-
 	// Test loading effective config with project config
-	effectiveConfig := handler.loadEffectiveConfig(globalConfig, tempDir, logger)
+	effectiveConfig := loadEffectiveConfig(globalConfig, tempDir, logger)
 
 	if effectiveConfig.ViewportWidth != 1920 {
 		t.Errorf("Effective ViewportWidth: got %f, want 1920 (project config should take priority)", effectiveConfig.ViewportWidth)
@@ -430,3 +465,74 @@ func TestHandlerLoadEffectiveConfig(t *testing.T) {
 		t.Errorf("Effective UnitPrecision: got %d, want 2 (project config should take priority)", effectiveConfig.UnitPrecision)
 	}
 }
+
+func TestWorkspaceConfigReload(t *testing.T) {
+	oldInterval := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = oldInterval }()
+
+	oldUserConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	defer func() {
+		if oldUserConfigDir != "" {
+			os.Setenv("XDG_CONFIG_HOME", oldUserConfigDir)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	logger := createTestLogger(t)
+	globalConfig, err := NewGlobalConfig(context.Background(), logger)
+	if err != nil {
+		t.Fatalf("Failed to create global config: %v", err)
+	}
+	defer globalConfig.Close()
+
+	handler, _, _ := NewHandler(context.Background(), nil, logger, globalConfig)
+	defer handler.Close()
+
+	folder := t.TempDir()
+	handler.addConfigFolder(folder)
+	uri := protocol.DocumentURI("file://" + filepath.Join(folder, "style.css"))
+
+	waitForViewport := func(expected float64) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if handler.getConfigForDocument(uri).ViewportWidth == expected {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("ViewportWidth: got %f, want %f", handler.getConfigForDocument(uri).ViewportWidth, expected)
+	}
+
+	waitForViewport(1440)
+
+	// .cssrem created after startup
+	cssremPath := filepath.Join(folder, ".cssrem")
+	if err := os.WriteFile(cssremPath, []byte(`{"vwDesign": 1920}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	waitForViewport(1920)
+
+	// global config changes re-merge workspace configs without a project viewport
+	if err := os.WriteFile(cssremPath, []byte(`{"vwDesign": 0}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	waitForViewport(1440)
+	if err := os.WriteFile(globalConfig.Path(), []byte(`{"vwDesign": 2560}`), 0644); err != nil {
+		t.Fatalf("Failed to write global config: %v", err)
+	}
+	waitForViewport(2560)
+
+	// .cssrem deleted
+	if err := os.WriteFile(cssremPath, []byte(`{"vwDesign": 375}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	waitForViewport(375)
+	if err := os.Remove(cssremPath); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	waitForViewport(2560)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/protocol"
+)
+
+const (
+	diagnosticSource = "px-to-vw-lsp"
+	diagnosticRawPx  = "raw-px"
+)
+
+var diagnosticSeverities = map[SchemaJsonDiagnostics]protocol.DiagnosticSeverity{
+	SchemaJsonDiagnosticsError:       protocol.DiagnosticSeverityError,
+	SchemaJsonDiagnosticsWarning:     protocol.DiagnosticSeverityWarning,
+	SchemaJsonDiagnosticsInformation: protocol.DiagnosticSeverityInformation,
+	SchemaJsonDiagnosticsHint:        protocol.DiagnosticSeverityHint,
+}
+
+// publishDiagnostics reports the raw px values of a document to the client.
+// Once diagnostics are off, or the document closed, they are cleared once
+// rather than published empty on every change.
+func (h *Handler) publishDiagnostics(ctx context.Context, uri protocol.DocumentURI) {
+	if h.client == nil {
+		return
+	}
+
+	params := &protocol.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: []protocol.Diagnostic{},
+	}
+	doc, open := h.documents.get(uri)
+	config := h.getConfigForDocument(uri)
+	_, enabled := diagnosticSeverities[config.Diagnostics]
+	enabled = enabled && open
+	if wasEnabled := h.documents.setDiagnosed(uri, enabled); !enabled && !wasEnabled {
+		// the client holds no diagnostics of the document to clear
+		return
+	}
+	if enabled {
+		params.Version = uint32(doc.version)
+		params.Diagnostics = rawPxDiagnostics(doc, config, h.getPositionEncoding())
+	}
+
+	err := h.client.PublishDiagnostics(ctx, params)
+	if err != nil {
+		log.Sugar().Warnf("Failed to publish diagnostics for %s: %v", uri, err)
+	}
+}
+
+// refreshDiagnostics publishes the diagnostics of the open documents under
+// folderPath again after their config changed, or of every open document
+// when folderPath is empty
+func (h *Handler) refreshDiagnostics(folderPath string) {
+	for uri := range h.documents.snapshot() {
+		if strings.HasPrefix(strings.TrimPrefix(string(uri), "file://"), folderPath) {
+			h.publishDiagnostics(h.ctx, uri)
+		}
+	}
+}
+
+// rawPxDiagnostics returns a diagnostic for every raw px value of a document
+func rawPxDiagnostics(doc *document, config *Config, encoding positionEncoding) []protocol.Diagnostic {
+	lines := doc.Lines()
+	diagnostics := []protocol.Diagnostic{}
+	severity, ok := diagnosticSeverities[config.Diagnostics]
+	if !ok {
+		return diagnostics
+	}
+
+	for _, raw := range findRawPx(doc, config) {
+		match := raw.conversion.From
+		diagnostics = append(diagnostics, protocol.Diagnostic{
+			Range:    encoding.lineRange(uint32(raw.line), lines[raw.line], match.Start, match.End),
+			Severity: severity,
+			Code:     diagnosticRawPx,
+			Source:   diagnosticSource,
+			Message:  raw.message(),
+		})
+	}
+	return diagnostics
+}
+
+// rawPx is a px literal on a line that should have been converted
+type rawPx struct {
+	line       int
+	conversion convert.Conversion
+}
+
+func (r rawPx) message() string {
+	return fmt.Sprintf("Raw px value %s, use %s", r.conversion.From.Text(), r.conversion.Text())
+}
+
+// findRawPx returns every px literal of a document that isn't in
+// ignoresViaCommand or used by an allowed property
+func findRawPx(doc *document, config *Config) []rawPx {
+	var found []rawPx
+	scan, index := doc.Scan()
+	for _, edit := range config.converter().PxToVwEditsOf(scan.Matches) {
+		i, edit := index.lineEdit(edit)
+		if isAllowedPxProperty(edit.Conversion.From.Property, config) {
+			continue
+		}
+		found = append(found, rawPx{line: i, conversion: edit.Conversion})
+	}
+	return found
+}
+
+func isAllowedPxProperty(property string, config *Config) bool {
+	if property == "" {
+		return false
+	}
+	for _, allowed := range config.AllowedPxProperties {
+		if strings.EqualFold(allowed, property) {
+			return true
+		}
+	}
+	return false
+}
+
+// rawPxQuickFixes returns a quick fix for each raw px diagnostic sent back by the client
+func rawPxQuickFixes(uri protocol.DocumentURI, doc *document, diagnostics []protocol.Diagnostic, config *Config, encoding positionEncoding) []protocol.CodeAction {
+	lines := doc.Lines()
+	converter := config.converter()
+	actions := []protocol.CodeAction{}
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Source != diagnosticSource || diagnostic.Code != diagnosticRawPx {
+			continue
+		}
+		if int(diagnostic.Range.Start.Line) >= len(lines) {
+			continue
+		}
+
+		line := lines[diagnostic.Range.Start.Line]
+		match, ok := unitAt(doc, int(diagnostic.Range.Start.Line), encoding.byteOffset(line, diagnostic.Range.Start.Character))
+		if !ok || match.Unit != "px" {
+			continue
+		}
+
+		conv := converter.PxToVwConversion(match)
+		actions = append(actions, protocol.CodeAction{
+			Title:       fmt.Sprintf("Convert %s → %s", match.Text(), conv.Text()),
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diagnostic},
+			IsPreferred: true,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					uri: {conversionEdit(diagnostic.Range.Start.Line, line, conv, converter, encoding)},
+				},
+			},
+		})
+	}
+	return actions
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestRawPxDiagnostics(t *testing.T) {
+	doc := newDocument("css", 1, ".card {\n  width: 348px;\n  border-width: 2px;\n  border: 1px solid;\n  margin: 10px 2rem;\n}")
+
+	tests := []struct {
+		name         string
+		config       Config
+		expectRanges [][2]uint32
+	}{
+		{
+			name:   "Diagnostics off",
+			config: Config{ViewportWidth: 1440, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsOff},
+		},
+		{
+			name:         "All px reported",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsWarning},
+			expectRanges: [][2]uint32{{1, 9}, {2, 16}, {3, 10}, {4, 10}},
+		},
+		{
+			name: "Ignores and allowed properties",
+			config: Config{
+				ViewportWidth:       1440,
+				UnitPrecision:       3,
+				Diagnostics:         SchemaJsonDiagnosticsError,
+				IgnoresViaCommand:   []string{"1px"},
+				AllowedPxProperties: []string{"border-width"},
+			},
+			expectRanges: [][2]uint32{{1, 9}, {4, 10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := rawPxDiagnostics(doc, &tt.config, positionEncodingUTF16)
+
+			if len(diagnostics) != len(tt.expectRanges) {
+				t.Fatalf("Expected %d diagnostics, got %d: %v", len(tt.expectRanges), len(diagnostics), diagnostics)
+			}
+			for i, diagnostic := range diagnostics {
+				start := diagnostic.Range.Start
+				if start.Line != tt.expectRanges[i][0] || start.Character != tt.expectRanges[i][1] {
+					t.Errorf("Diagnostic %d start: got %d:%d, want %d:%d",
+						i, start.Line, start.Character, tt.expectRanges[i][0], tt.expectRanges[i][1])
+				}
+				if diagnostic.Severity != diagnosticSeverities[tt.config.Diagnostics] {
+					t.Errorf("Diagnostic %d severity: got %v", i, diagnostic.Severity)
+				}
+			}
+		})
+	}
+
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsWarning}
+	if message := rawPxDiagnostics(doc, &config, positionEncodingUTF16)[0].Message; message != "Raw px value 348px, use 24.167vw" {
+		t.Errorf("Message: got %q", message)
+	}
+}
+
+func TestRawPxDiagnosticsMultilineProperty(t *testing.T) {
+	doc := newDocument("css", 1, ".a {\n  border-width:\n    1px 2px;\n  margin:\n    4px;\n}")
+	config := Config{
+		ViewportWidth:       1440,
+		UnitPrecision:       3,
+		Diagnostics:         SchemaJsonDiagnosticsWarning,
+		AllowedPxProperties: []string{"border-width"},
+	}
+
+	diagnostics := rawPxDiagnostics(doc, &config, positionEncodingUTF16)
+	if len(diagnostics) != 1 || diagnostics[0].Range.Start.Line != 4 {
+		t.Errorf("Expected only the margin reported, got %v", diagnostics)
+	}
+}
+
+func TestRawPxDiagnosticsSkipNonValues(t *testing.T) {
+	text := "/*\n  .old { width: 12px; }\n*/\n.icon-24px {\n  background: url(icon-24px.svg);\n  content: \"8px\";\n  width: 24px; // was 20px\n}"
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsWarning}
+	diagnostics := rawPxDiagnostics(newDocument("scss", 1, text), &config, positionEncodingUTF16)
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+	}
+	if start := diagnostics[0].Range.Start; start.Line != 6 || start.Character != 9 {
+		t.Errorf("Diagnostic start: got %d:%d, want 6:9", start.Line, start.Character)
+	}
+}
+
+func TestRawPxDiagnosticsLineComments(t *testing.T) {
+	text := "a {\n  width: 24px; // 20px\n}"
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsWarning}
+
+	// `//` only starts a comment in SCSS and Less
+	for language, expected := range map[protocol.LanguageIdentifier]int{"css": 2, "scss": 1, "less": 1} {
+		if diagnostics := rawPxDiagnostics(newDocument(language, 1, text), &config, positionEncodingUTF16); len(diagnostics) != expected {
+			t.Errorf("%s: expected %d diagnostics, got %v", language, expected, diagnostics)
+		}
+	}
+}
+
+func TestDiagnosticsPublishAndQuickFix(t *testing.T) {
+	conn := &recordingConn{}
+	handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+	handler.configs["/project"] = &Config{ViewportWidth: 1440, UnitPrecision: 3, Diagnostics: SchemaJsonDiagnosticsWarning}
+	uri := protocol.DocumentURI("file:///project/style.css")
+
+	err := handler.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, LanguageID: "css", Text: "a {\n  width: 348px;\n}"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	published := conn.sent(protocol.MethodTextDocumentPublishDiagnostics)
+	if len(published) != 1 {
+		t.Fatalf("Expected 1 publishDiagnostics, got %d", len(published))
+	}
+	diagnostics := published[0].params.(*protocol.PublishDiagnosticsParams).Diagnostics
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %d", len(diagnostics))
+	}
+
+	actions, err := handler.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        diagnostics[0].Range,
+		Context:      protocol.CodeActionContext{Diagnostics: diagnostics},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actions) == 0 || actions[0].Kind != protocol.QuickFix {
+		t.Fatalf("Expected a quick fix first, got %v", actions)
+	}
+	if edits := actions[0].Edit.Changes[uri]; len(edits) != 1 || edits[0].NewText != "24.167vw" {
+		t.Errorf("Quick fix edits: got %v", edits)
+	}
+
+	if err := handler.DidClose(context.Background(), &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	published = conn.sent(protocol.MethodTextDocumentPublishDiagnostics)
+	if cleared := published[len(published)-1].params.(*protocol.PublishDiagnosticsParams); len(cleared.Diagnostics) != 0 {
+		t.Errorf("Expected diagnostics to be cleared on close, got %v", cleared.Diagnostics)
+	}
+}
+
+func TestDiagnosticsRefresh(t *testing.T) {
+	conn := &recordingConn{}
+	handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+	defer handler.Close()
+	handler.clientWatchesFiles = true
+	folder := t.TempDir()
+	handler.addConfigFolder(folder)
+	uri := protocol.DocumentURI("file://" + filepath.Join(folder, "style.css"))
+	published := func() int { return len(conn.sent(protocol.MethodTextDocumentPublishDiagnostics)) }
+
+	writeConfig := func(diagnostics string) {
+		cssrem := `{"diagnostics": "` + diagnostics + `"}`
+		if err := os.WriteFile(filepath.Join(folder, ".cssrem"), []byte(cssrem), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		handler.reloadConfigFolder(folder)
+	}
+
+	// diagnostics are off by default, so nothing is published
+	err := handler.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, LanguageID: "css", Version: 1, Text: "a { width: 348px; }"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := published(); n != 0 {
+		t.Fatalf("Expected no publish with diagnostics off, got %d", n)
+	}
+
+	writeConfig("warning")
+	sent := conn.sent(protocol.MethodTextDocumentPublishDiagnostics)
+	if len(sent) != 1 || len(sent[0].params.(*protocol.PublishDiagnosticsParams).Diagnostics) != 1 {
+		t.Fatalf("Expected the reload to publish 1 diagnostic, got %v", sent)
+	}
+
+	// turning them off clears them once
+	writeConfig("off")
+	sent = conn.sent(protocol.MethodTextDocumentPublishDiagnostics)
+	if len(sent) != 2 || len(sent[1].params.(*protocol.PublishDiagnosticsParams).Diagnostics) != 0 {
+		t.Fatalf("Expected a clearing publish, got %v", sent)
+	}
+	err = handler.didChange(context.Background(), &didChangeParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			Version:                2,
+		},
+		ContentChanges: []contentChange{{Text: "a { width: 10px; }"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := published(); n != 2 {
+		t.Errorf("Expected no more publishes with diagnostics off, got %d", n)
+	}
+}
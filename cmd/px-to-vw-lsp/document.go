@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/protocol"
+)
+
+var errDocumentNotOpen = errors.New("document not open")
+
+// documentStore holds the open documents. A stored document is never
+// modified, changes replace it with a new one, so a *document returned by get
+// can be read without holding the lock. Documents are keyed by documentKey,
+// so every spelling of a uri finds the same document.
+type documentStore struct {
+	mu   sync.RWMutex
+	docs map[protocol.DocumentURI]*document
+	// uris holds the uri of each document as the client opened it
+	uris map[protocol.DocumentURI]protocol.DocumentURI
+	// cursors holds the line the cursor was last seen on in each document
+	cursors map[protocol.DocumentURI]uint32
+	// diagnosed holds the documents the client was last sent diagnostics
+	// for, which outlive close until they are cleared
+	diagnosed map[protocol.DocumentURI]bool
+}
+
+func newDocumentStore() *documentStore {
+	return &documentStore{
+		docs:      make(map[protocol.DocumentURI]*document),
+		uris:      make(map[protocol.DocumentURI]protocol.DocumentURI),
+		cursors:   make(map[protocol.DocumentURI]uint32),
+		diagnosed: make(map[protocol.DocumentURI]bool),
+	}
+}
+
+func (s *documentStore) get(uri protocol.DocumentURI) (*document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	doc, ok := s.docs[documentKey(uri)]
+	return doc, ok
+}
+
+func (s *documentStore) open(uri protocol.DocumentURI, doc *document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[documentKey(uri)] = doc
+	s.uris[documentKey(uri)] = uri
+}
+
+// change applies content changes to an open document and returns the new
+// document. A document the changes can't be applied to is dropped, as it no
+// longer matches the client.
+func (s *documentStore) change(uri protocol.DocumentURI, version int32, changes []contentChange, encoding positionEncoding) (*document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := documentKey(uri)
+	doc, ok := s.docs[key]
+	if !ok {
+		return nil, errDocumentNotOpen
+	}
+	next, err := doc.apply(version, changes, encoding)
+	if err != nil {
+		delete(s.docs, key)
+		delete(s.uris, key)
+		return nil, err
+	}
+	s.docs[key] = next
+	return next, nil
+}
+
+func (s *documentStore) close(uri protocol.DocumentURI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := documentKey(uri)
+	delete(s.docs, key)
+	delete(s.uris, key)
+	delete(s.cursors, key)
+}
+
+// moveCursor records the cursor line of an open document, reporting whether it moved
+func (s *documentStore) moveCursor(uri protocol.DocumentURI, line uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := documentKey(uri)
+	if _, ok := s.docs[key]; !ok {
+		return false
+	}
+	old, ok := s.cursors[key]
+	s.cursors[key] = line
+	return !ok || old != line
+}
+
+// setDiagnosed records whether the client was sent diagnostics for a
+// document, returning whether it had been before
+func (s *documentStore) setDiagnosed(uri protocol.DocumentURI, diagnosed bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := documentKey(uri)
+	was := s.diagnosed[key]
+	if diagnosed {
+		s.diagnosed[key] = true
+	} else {
+		delete(s.diagnosed, key)
+	}
+	return was
+}
+
+// cursor returns the cursor line of a document, if it has been seen
+func (s *documentStore) cursor(uri protocol.DocumentURI) (uint32, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	line, ok := s.cursors[documentKey(uri)]
+	return line, ok
+}
+
+// snapshot returns every open document by the uri the client opened it with
+func (s *documentStore) snapshot() map[protocol.DocumentURI]*document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make(map[protocol.DocumentURI]*document, len(s.docs))
+	for key, doc := range s.docs {
+		docs[s.uris[key]] = doc
+	}
+	return docs
+}
+
+// documentKey normalises a file uri, so the spellings clients use for one
+// path, percent-encoded or not and with either case of a drive letter, are
+// the same key. Other uris are left alone.
+func documentKey(uri protocol.DocumentURI) protocol.DocumentURI {
+	parsed, err := url.Parse(string(uri))
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	path := parsed.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = "/" + strings.ToLower(path[1:2]) + path[2:]
+	}
+	return protocol.DocumentURI((&url.URL{Scheme: "file", Path: path}).String())
+}
+
+// document is an open text document stored as a slice of lines. Edits only
+// re-split the lines they touch and build a new slice, so a []string handed
+// out by Lines stays valid after later changes.
+type document struct {
+	languageID protocol.LanguageIdentifier
+	version    int32
+	lines      []string
+
+	// scan and index are found on first use, once per version
+	scanOnce sync.Once
+	scan     *convert.Scan
+	index    lineIndex
+}
+
+func newDocument(languageID protocol.LanguageIdentifier, version int32, text string) *document {
+	return &document{
+		languageID: languageID,
+		version:    version,
+		lines:      strings.Split(text, "\n"),
+	}
+}
+
+// Lines returns the current lines of the document, which must not be modified
+func (d *document) Lines() []string {
+	return d.lines
+}
+
+// Text returns the full text of the document
+func (d *document) Text() string {
+	return strings.Join(d.lines, "\n")
+}
+
+// Scan returns the tokens and unit literals of the document, with the
+// index mapping their offsets back to lines. Requests between changes share
+// them rather than each reading the whole document again.
+func (d *document) Scan() (*convert.Scan, lineIndex) {
+	d.scanOnce.Do(func() {
+		d.scan = convert.NewScan(d.Text(), convert.DialectFor(string(d.languageID)))
+		d.index = newLineIndex(d.lines)
+	})
+	return d.scan, d.index
+}
+
+// contentChange is a textDocument/didChange content change. Unlike
+// protocol.TextDocumentContentChangeEvent the range is optional, so a full
+// text replacement can be told apart from an edit at 0:0.
+type contentChange struct {
+	Range *protocol.Range `json:"range,omitempty"`
+	Text  string          `json:"text"`
+}
+
+// didChangeParams are the textDocument/didChange params with optional ranges
+type didChangeParams struct {
+	TextDocument   protocol.VersionedTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []contentChange                          `json:"contentChanges"`
+}
+
+// apply returns a copy of the document at version with the content changes
+// applied in order
+func (d *document) apply(version int32, changes []contentChange, encoding positionEncoding) (*document, error) {
+	next := &document{languageID: d.languageID, version: version, lines: d.lines}
+	for i, change := range changes {
+		if change.Range == nil {
+			next.lines = strings.Split(change.Text, "\n")
+			continue
+		}
+		if err := next.applyRange(*change.Range, change.Text, encoding); err != nil {
+			return nil, fmt.Errorf("content change %d: %w", i, err)
+		}
+	}
+	return next, nil
+}
+
+// applyRange replaces the text in rng with text
+func (d *document) applyRange(rng protocol.Range, text string, encoding positionEncoding) error {
+	start, end := rng.Start, rng.End
+	if start.Line > end.Line || (start.Line == end.Line && start.Character > end.Character) {
+		return fmt.Errorf("invalid range %v", rng)
+	}
+	if int(start.Line) > len(d.lines) || (int(start.Line) == len(d.lines) && start.Character > 0) {
+		return fmt.Errorf("range start %d:%d outside document of %d lines", start.Line, start.Character, len(d.lines))
+	}
+
+	startLine, startOffset := d.locate(start, encoding)
+	endLine, endOffset := d.locate(end, encoding)
+	prefix := d.lines[startLine][:startOffset]
+	suffix := d.lines[endLine][endOffset:]
+	replaced := strings.Split(prefix+text+suffix, "\n")
+
+	lines := make([]string, 0, len(d.lines)-(endLine-startLine+1)+len(replaced))
+	lines = append(lines, d.lines[:startLine]...)
+	lines = append(lines, replaced...)
+	lines = append(lines, d.lines[endLine+1:]...)
+	d.lines = lines
+	return nil
+}
+
+// locate returns the line index and byte offset of pos. Editors address the
+// end of the document as the start of the line after it.
+func (d *document) locate(pos protocol.Position, encoding positionEncoding) (int, int) {
+	if last := len(d.lines) - 1; int(pos.Line) > last {
+		return last, len(d.lines[last])
+	}
+	return int(pos.Line), encoding.byteOffset(d.lines[pos.Line], pos.Character)
+}
+
+// lineIndex holds the byte offset each line starts at in the lines joined
+// with "\n". The conversion engine scans whole documents so comments and
+// rules spanning lines are understood, and its offsets map back to lines.
+type lineIndex []int
+
+func newLineIndex(lines []string) lineIndex {
+	starts := make(lineIndex, len(lines))
+	offset := 0
+	for i, line := range lines {
+		starts[i] = offset
+		offset += len(line) + 1
+	}
+	return starts
+}
+
+// offset returns the document offset of the byte offset col of a line
+func (x lineIndex) offset(line, col int) int {
+	return x[line] + col
+}
+
+// line returns the line containing a document offset
+func (x lineIndex) line(offset int) int {
+	return sort.Search(len(x), func(i int) bool { return x[i] > offset }) - 1
+}
+
+// position returns the position of a document offset in the lines
+func (x lineIndex) position(lines []string, offset int, encoding positionEncoding) protocol.Position {
+	line := x.line(offset)
+	return protocol.Position{Line: uint32(line), Character: encoding.character(lines[line], offset-x[line])}
+}
+
+// lineMatch returns the line of match, with its offsets made relative to the line
+func (x lineIndex) lineMatch(match convert.Match) (int, convert.Match) {
+	line := x.line(match.Start)
+	match.Start -= x[line]
+	match.End -= x[line]
+	return line, match
+}
+
+// lineEdit returns the line of edit, with its offsets made relative to the
+// line. Edits replace a single literal, so they never span lines.
+func (x lineIndex) lineEdit(edit convert.Edit) (int, convert.Edit) {
+	line, from := x.lineMatch(edit.Conversion.From)
+	edit.Start -= x[line]
+	edit.End -= x[line]
+	edit.Conversion.From = from
+	return line, edit
+}
+
+// unitAt returns the unit literal touching the byte offset col of a line
+func unitAt(doc *document, line, col int) (convert.Match, bool) {
+	scan, index := doc.Scan()
+	match, ok := scan.UnitAt(index.offset(line, col))
+	if !ok {
+		return convert.Match{}, false
+	}
+	_, match = index.lineMatch(match)
+	return match, true
+}
+
+// unitBefore returns the unit literal ending at the byte offset col of a line
+func unitBefore(doc *document, line, col int) (convert.Match, bool) {
+	scan, index := doc.Scan()
+	match, ok := scan.UnitBefore(index.offset(line, col))
+	if !ok {
+		return convert.Match{}, false
+	}
+	_, match = index.lineMatch(match)
+	return match, true
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func rangeOf(startLine, startChar, endLine, endChar uint32) *protocol.Range {
+	return &protocol.Range{
+		Start: protocol.Position{Line: startLine, Character: startChar},
+		End:   protocol.Position{Line: endLine, Character: endChar},
+	}
+}
+
+func TestDocumentApply(t *testing.T) {
+	text := ".a {\n  width: 348px;\n}"
+
+	tests := []struct {
+		name      string
+		changes   []contentChange
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "Insert within a line",
+			changes:  []contentChange{{Range: rangeOf(1, 12, 1, 12), Text: "0"}},
+			expected: ".a {\n  width: 3480px;\n}",
+		},
+		{
+			name:     "Replace a value",
+			changes:  []contentChange{{Range: rangeOf(1, 9, 1, 14), Text: "24.167vw"}},
+			expected: ".a {\n  width: 24.167vw;\n}",
+		},
+		{
+			name:     "Insert new lines",
+			changes:  []contentChange{{Range: rangeOf(1, 15, 1, 15), Text: "\n  height: 10px;"}},
+			expected: ".a {\n  width: 348px;\n  height: 10px;\n}",
+		},
+		{
+			name:     "Delete across lines",
+			changes:  []contentChange{{Range: rangeOf(0, 4, 2, 0), Text: ""}},
+			expected: ".a {}",
+		},
+		{
+			name:     "Insert at the start",
+			changes:  []contentChange{{Range: rangeOf(0, 0, 0, 0), Text: "/* x */\n"}},
+			expected: "/* x */\n.a {\n  width: 348px;\n}",
+		},
+		{
+			name:     "Append past the last line",
+			changes:  []contentChange{{Range: rangeOf(3, 0, 3, 0), Text: "\n"}},
+			expected: ".a {\n  width: 348px;\n}\n",
+		},
+		{
+			name:     "Full text replacement",
+			changes:  []contentChange{{Text: ".b { margin: 1px; }"}},
+			expected: ".b { margin: 1px; }",
+		},
+		{
+			name: "Multiple changes applied in order",
+			changes: []contentChange{
+				{Range: rangeOf(1, 9, 1, 14), Text: "10px"},
+				{Range: rangeOf(1, 2, 1, 7), Text: "height"},
+				{Range: rangeOf(2, 1, 2, 1), Text: "\n"},
+			},
+			expected: ".a {\n  height: 10px;\n}\n",
+		},
+		{
+			name:     "Full text then ranged change",
+			changes:  []contentChange{{Text: "a\nb"}, {Range: rangeOf(1, 0, 1, 1), Text: "c"}},
+			expected: "a\nc",
+		},
+		{
+			name:      "Range outside the document",
+			changes:   []contentChange{{Range: rangeOf(7, 0, 7, 0), Text: "x"}},
+			expectErr: true,
+		},
+		{
+			name:      "Inverted range",
+			changes:   []contentChange{{Range: rangeOf(1, 5, 1, 2), Text: "x"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newDocument("css", 1, text)
+			before := doc.Lines()
+
+			next, err := doc.apply(2, tt.changes, positionEncodingUTF16)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %q", next.Text())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if next.Text() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, next.Text())
+			}
+			if next.version != 2 {
+				t.Errorf("Expected version 2, got %d", next.version)
+			}
+			if doc.Text() != text || doc.version != 1 {
+				t.Errorf("Original document was modified: %q (version %d)", doc.Text(), doc.version)
+			}
+			if strings.Join(before, "\n") != text {
+				t.Errorf("Earlier lines were modified: %q", strings.Join(before, "\n"))
+			}
+		})
+	}
+}
+
+func TestExtendedHandlerDidChange(t *testing.T) {
+	handler, uri := newTestHandler(t, ".a { width: 348px; }", Config{ViewportWidth: 1440, UnitPrecision: 3})
+	next := func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		t.Errorf("Unexpected call to the next handler for %s", req.Method())
+		return nil
+	}
+	h := extendedHandler(handler, next)
+
+	notify := func(params string) {
+		t.Helper()
+		req, err := jsonrpc2.NewNotification(protocol.MethodTextDocumentDidChange, json.RawMessage(params))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = h(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			return nil
+		}, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a change without a range replaces the document
+	notify(`{"textDocument":{"uri":"` + string(uri) + `","version":2},"contentChanges":[{"text":".b {\n  margin: 10px;\n}"}]}`)
+	if doc, _ := handler.documents.get(uri); doc.Text() != ".b {\n  margin: 10px;\n}" {
+		t.Fatalf("Full change: got %q", doc.Text())
+	}
+
+	// a range at 0:0 is an insertion
+	notify(`{"textDocument":{"uri":"` + string(uri) + `","version":3},"contentChanges":[` +
+		`{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"text":"/* x */\n"},` +
+		`{"range":{"start":{"line":2,"character":10},"end":{"line":2,"character":12}},"text":"20"}]}`)
+	doc, _ := handler.documents.get(uri)
+	if got := doc.Text(); got != "/* x */\n.b {\n  margin: 20px;\n}" {
+		t.Errorf("Incremental change: got %q", got)
+	}
+	if doc.version != 3 {
+		t.Errorf("Expected version 3, got %d", doc.version)
+	}
+}
+
+func TestDocumentScan(t *testing.T) {
+	doc := newDocument("css", 1, ".a {\n  width: 10px;\n}")
+	scan, _ := doc.Scan()
+	if again, _ := doc.Scan(); again != scan {
+		t.Error("Expected the scan to be reused within a version")
+	}
+	if match, ok := unitAt(doc, 1, 10); !ok || match.Text() != "10px" || match.Start != 9 {
+		t.Errorf("unitAt: got %+v, %v", match, ok)
+	}
+
+	next, err := doc.apply(2, []contentChange{{Range: rangeOf(1, 9, 1, 11), Text: "20"}}, positionEncodingUTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextScan, _ := next.Scan(); nextScan == scan {
+		t.Error("Expected a new scan after a change")
+	}
+	if match, ok := unitBefore(next, 1, 13); !ok || match.Text() != "20px" {
+		t.Errorf("unitBefore after the change: got %+v, %v", match, ok)
+	}
+}
+
+func TestDocumentKey(t *testing.T) {
+	tests := []struct {
+		a, b protocol.DocumentURI
+	}{
+		{"file:///project/my%20style.css", "file:///project/my style.css"},
+		{"file:///project/%C3%A9.css", "file:///project/é.css"},
+		{"file:///C:/project/style.css", "file:///c%3A/project/style.css"},
+		{"untitled:Untitled-1", "untitled:Untitled-1"},
+	}
+
+	for _, tt := range tests {
+		if documentKey(tt.a) != documentKey(tt.b) {
+			t.Errorf("Expected %q and %q to match, got %q and %q", tt.a, tt.b, documentKey(tt.a), documentKey(tt.b))
+		}
+	}
+
+	store := newDocumentStore()
+	store.open("file:///project/my%20style.css", newDocument("css", 1, ""))
+	if _, ok := store.get("file:///project/my style.css"); !ok {
+		t.Error("Expected the document found by another spelling of its uri")
+	}
+	if docs := store.snapshot(); docs["file:///project/my%20style.css"] == nil {
+		t.Errorf("Expected the snapshot to keep the client's uri, got %v", docs)
+	}
+}
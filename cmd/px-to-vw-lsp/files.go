@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultLanguages are converted when the cssrem `languages` setting is empty
+var defaultLanguages = []string{"css", "scss", "sass", "less", "stylus", "wxss"}
+
+// extensionLanguages maps file extensions to language ids where they differ
+var extensionLanguages = map[string]string{
+	".styl": "stylus",
+	".js":   "javascript",
+	".jsx":  "javascriptreact",
+	".ts":   "typescript",
+	".tsx":  "typescriptreact",
+}
+
+// skippedDirs are never walked when collecting files
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+}
+
+// languageForPath guesses the language id of a file from its extension
+func languageForPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if language, ok := extensionLanguages[ext]; ok {
+		return language
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// languageEnabled reports whether files of language are converted under config
+func languageEnabled(language string, config *Config) bool {
+	languages := config.Languages
+	if len(languages) == 0 {
+		languages = defaultLanguages
+	}
+	for _, enabled := range languages {
+		if enabled == language {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnoredPath reports whether rel, a slash separated path relative to the
+// workspace root, matches one of the cssrem `ignores` globs
+func isIgnoredPath(rel string, config *Config) bool {
+	for _, pattern := range config.Ignores {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash separated path against a glob supporting `*`, `?`
+// and `**`. Patterns without a slash are matched against the base name.
+func matchGlob(pattern, path string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if !strings.Contains(pattern, "/") {
+		path = path[strings.LastIndex(path, "/")+1:]
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// collectFiles walks root and returns the files config allows converting.
+// Directories that can't be read are skipped rather than ending the walk.
+func collectFiles(ctx context.Context, root string, config *Config) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Sugar().Warnf("Skipping %s: %v", path, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if path != root && (skippedDirs[d.Name()] || isIgnoredPath(rel, config)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isIgnoredPath(rel, config) || !languageEnabled(languageForPath(path), config) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		expect  bool
+	}{
+		{"**/vendor/**", "vendor/a.css", true},
+		{"**/vendor/**", "src/vendor/a.css", true},
+		{"**/vendor/**", "src/app.css", false},
+		{"*.min.css", "dist/app.min.css", true},
+		{"*.min.css", "dist/app.css", false},
+		{"dist/*.css", "dist/app.css", true},
+		{"dist/*.css", "dist/sub/app.css", false},
+		{"./legacy/**", "legacy/old.scss", true},
+		{"src/?.css", "src/a.css", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.path); got != tt.expect {
+				t.Errorf("matchGlob(%q, %q): got %v, want %v", tt.pattern, tt.path, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestCollectFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{
+		"app.css",
+		"theme.scss",
+		"main.js",
+		"vendor/reset.css",
+		"node_modules/lib/lib.css",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("a { width: 10px; }"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		expect []string
+	}{
+		{
+			name:   "Default languages",
+			config: Config{},
+			expect: []string{"app.css", "theme.scss", "vendor/reset.css"},
+		},
+		{
+			name:   "Ignores",
+			config: Config{Ignores: []string{"**/vendor/**"}},
+			expect: []string{"app.css", "theme.scss"},
+		},
+		{
+			name:   "Languages",
+			config: Config{Languages: []string{"scss"}},
+			expect: []string{"theme.scss"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := collectFiles(context.Background(), root, &tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var rel []string
+			for _, file := range files {
+				r, _ := filepath.Rel(root, file)
+				rel = append(rel, filepath.ToSlash(r))
+			}
+			sort.Strings(rel)
+
+			if len(rel) != len(tt.expect) {
+				t.Fatalf("Files: got %v, want %v", rel, tt.expect)
+			}
+			for i := range rel {
+				if rel[i] != tt.expect[i] {
+					t.Errorf("Files: got %v, want %v", rel, tt.expect)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCollectFilesSkipsUnreadable(t *testing.T) {
+	log = createTestLogger(t)
+	root := t.TempDir()
+	for _, name := range []string{"app.css", "locked/hidden.css"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("a { width: 10px; }"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	// a missing root has no files rather than failing
+	if files, err := collectFiles(context.Background(), filepath.Join(root, "missing"), &Config{}); err != nil || len(files) != 0 {
+		t.Errorf("Missing root: got %v, %v", files, err)
+	}
+
+	locked := filepath.Join(root, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatalf("Failed to lock directory: %v", err)
+	}
+	defer os.Chmod(locked, 0755)
+	if _, err := os.ReadDir(locked); err == nil {
+		t.Skip("directory permissions aren't enforced for this user")
+	}
+
+	files, err := collectFiles(context.Background(), root, &Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "app.css" {
+		t.Errorf("Expected only app.css, got %v", files)
+	}
+}
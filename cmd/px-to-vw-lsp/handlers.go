@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
 )
@@ -14,52 +17,137 @@ import (
 var log *zap.Logger
 
 type Handler struct {
-	protocol.Server
-	documents        map[protocol.DocumentURI][]string
+	unimplementedServer
+	conn      jsonrpc2.Conn
+	client    protocol.Client
+	documents *documentStore
+	// ctx is cancelled on shutdown to stop background work
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stateMu guards the lifecycle and the session state negotiated in initialize
+	stateMu         sync.RWMutex
+	state           serverState
+	exitCode        int
+	encoding        positionEncoding
+	progressCancels map[string]context.CancelFunc
+	// inlayHintRefresh is set when the client supports workspace/inlayHint/refresh
+	inlayHintRefresh bool
+	// configsMu guards the workspace folders and their configs and watchers
+	configsMu        sync.RWMutex
 	workspaceFolders []protocol.WorkspaceFolder
 	configs          map[string]*Config
-	globalConfig     *GlobalConfig
+	configWatchers   map[string]*fileWatcher
+	// clientWatchesFiles is set when the client can register workspace/didChangeWatchedFiles
+	clientWatchesFiles bool
+	// clientWatchesRelativePatterns is set when the client can watch files
+	// relative to a base uri, like the global config outside the workspace
+	clientWatchesRelativePatterns bool
+	globalConfig                  *GlobalConfig
 }
 
-func NewHandler(ctx context.Context, server protocol.Server, logger *zap.Logger, globalConfig *GlobalConfig) (*Handler, context.Context, error) {
+func NewHandler(ctx context.Context, conn jsonrpc2.Conn, logger *zap.Logger, globalConfig *GlobalConfig) (*Handler, context.Context, error) {
 	log = logger
-	return &Handler{
-		Server:       server,
-		documents:    make(map[protocol.DocumentURI][]string),
-		configs:      make(map[string]*Config),
-		globalConfig: globalConfig,
-	}, ctx, nil
+	var client protocol.Client
+	if conn != nil {
+		client = protocol.ClientDispatcher(conn, logger)
+	}
+	h := &Handler{
+		conn:            conn,
+		client:          client,
+		documents:       newDocumentStore(),
+		exitCode:        1,
+		encoding:        positionEncodingUTF16,
+		progressCancels: make(map[string]context.CancelFunc),
+		configs:         make(map[string]*Config),
+		configWatchers:  make(map[string]*fileWatcher),
+		globalConfig:    globalConfig,
+	}
+	h.ctx, h.cancel = context.WithCancel(ctx)
+	if globalConfig != nil {
+		globalConfig.OnChange(h.reloadAllConfigs)
+	}
+	return h, ctx, nil
+}
+
+// initializeResult is protocol.InitializeResult with the LSP 3.17 capabilities
+type initializeResult struct {
+	Capabilities serverCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
+}
+
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	PositionEncoding  positionEncoding `json:"positionEncoding,omitempty"`
+	InlayHintProvider bool             `json:"inlayHintProvider,omitempty"`
 }
 
 func (h *Handler) Initialize(ctx context.Context, params *protocol.InitializeParams) (*protocol.InitializeResult, error) {
-	log.Sugar().Infof("initialize: rootUri=%s, workspaceFolders=%d",
-		params.RootURI, len(params.WorkspaceFolders))
+	result, err := h.initialize(ctx, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.InitializeResult{
+		Capabilities: result.Capabilities.ServerCapabilities,
+		ServerInfo:   result.ServerInfo,
+	}, nil
+}
+
+// initialize handles the initialize request, with the LSP 3.17 capabilities
+// from the client's general capabilities
+func (h *Handler) initialize(ctx context.Context, params *protocol.InitializeParams, capabilities *initializeCapabilities) (*initializeResult, error) {
+	if capabilities == nil {
+		capabilities = &initializeCapabilities{}
+	}
+	encoding := negotiatePositionEncoding(capabilities.Capabilities.General.PositionEncodings)
+	log.Sugar().Infof("initialize: rootUri=%s, workspaceFolders=%d, positionEncoding=%s",
+		params.RootURI, len(params.WorkspaceFolders), encoding)
+
+	h.stateMu.Lock()
+	if h.state != stateUninitialized {
+		h.stateMu.Unlock()
+		return nil, errAlreadyInitialized
+	}
+	h.state = stateRunning
+	h.encoding = encoding
+	h.inlayHintRefresh = capabilities.Capabilities.Workspace.InlayHint.RefreshSupport
+	h.stateMu.Unlock()
+
+	if workspace := params.Capabilities.Workspace; workspace != nil && workspace.DidChangeWatchedFiles != nil {
+		h.configsMu.Lock()
+		h.clientWatchesFiles = workspace.DidChangeWatchedFiles.DynamicRegistration
+		h.clientWatchesRelativePatterns = capabilities.Capabilities.Workspace.DidChangeWatchedFiles.RelativePatternSupport
+		h.configsMu.Unlock()
+	}
 
 	if params.WorkspaceFolders != nil && len(params.WorkspaceFolders) > 0 {
-		h.workspaceFolders = params.WorkspaceFolders
+		h.configsMu.Lock()
+		h.workspaceFolders = append([]protocol.WorkspaceFolder(nil), params.WorkspaceFolders...)
+		h.configsMu.Unlock()
 		for _, folder := range params.WorkspaceFolders {
-			folderPath := strings.TrimPrefix(string(folder.URI), "file://")
-			config := h.loadEffectiveConfig(h.globalConfig, folderPath, log)
-			h.configs[folderPath] = &config
-			log.Sugar().Infof("Loaded effective config for workspace folder: %s (viewport: %.0f, precision: %d)",
-				folderPath, config.ViewportWidth, config.UnitPrecision)
+			h.addConfigFolder(strings.TrimPrefix(string(folder.URI), "file://"))
 		}
 	} else if params.RootURI != "" {
-		rootPath := strings.TrimPrefix(string(params.RootURI), "file://")
-		config := h.loadEffectiveConfig(h.globalConfig, rootPath, log)
-		h.configs[rootPath] = &config
+		h.addConfigFolder(strings.TrimPrefix(string(params.RootURI), "file://"))
 		log.Sugar().Warnf("Using deprecated RootURI parameter for initialization")
 	}
 
 	supported := true
-	return &protocol.InitializeResult{
-		Capabilities: protocol.ServerCapabilities{
+	return &initializeResult{
+		Capabilities: serverCapabilities{ServerCapabilities: protocol.ServerCapabilities{
 			TextDocumentSync: &protocol.TextDocumentSyncOptions{
 				OpenClose: true,
-				Change:    protocol.TextDocumentSyncKindFull,
+				Change:    protocol.TextDocumentSyncKindIncremental,
 			},
 			CompletionProvider: &protocol.CompletionOptions{
-				TriggerCharacters: []string{"x"},
+				TriggerCharacters: []string{"x", "w", "m"},
+			},
+			HoverProvider: true,
+			CodeActionProvider: &protocol.CodeActionOptions{
+				CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix, protocol.RefactorRewrite},
+			},
+			CodeLensProvider: &protocol.CodeLensOptions{},
+			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+				Commands: []string{commandConvertWorkspace, commandConvertBlock},
 			},
 			Workspace: &protocol.ServerCapabilitiesWorkspace{
 				WorkspaceFolders: &protocol.ServerCapabilitiesWorkspaceFolders{
@@ -67,7 +155,7 @@ func (h *Handler) Initialize(ctx context.Context, params *protocol.InitializePar
 					ChangeNotifications: "workspace/didChangeWorkspaceFolders",
 				},
 			},
-		},
+		}, PositionEncoding: encoding, InlayHintProvider: true},
 		ServerInfo: &protocol.ServerInfo{
 			Name:    "px-to-vw-lsp",
 			Version: "0.1.0",
@@ -75,38 +163,63 @@ func (h *Handler) Initialize(ctx context.Context, params *protocol.InitializePar
 	}, nil
 }
 
+// getPositionEncoding returns the position encoding agreed with the client
+func (h *Handler) getPositionEncoding() positionEncoding {
+	h.stateMu.RLock()
+	defer h.stateMu.RUnlock()
+	return h.encoding
+}
+
 func (h *Handler) DidChangeWorkspaceFolders(ctx context.Context, params *protocol.DidChangeWorkspaceFoldersParams) error {
 	log.Sugar().Infof("didChangeWorkspaceFolders: %v", params)
 
 	for _, removed := range params.Event.Removed {
-		removedPath := strings.TrimPrefix(string(removed.URI), "file://")
-		delete(h.configs, removedPath)
+		h.removeConfigFolder(strings.TrimPrefix(string(removed.URI), "file://"))
+		h.configsMu.Lock()
 		for i, folder := range h.workspaceFolders {
 			if folder.URI == removed.URI {
-				h.workspaceFolders = append(h.workspaceFolders[:i], h.workspaceFolders[i+1:]...)
+				h.workspaceFolders = append(h.workspaceFolders[:i:i], h.workspaceFolders[i+1:]...)
 				break
 			}
 		}
+		h.configsMu.Unlock()
 	}
 
 	for _, added := range params.Event.Added {
+		h.configsMu.Lock()
 		h.workspaceFolders = append(h.workspaceFolders, added)
-		addedPath := strings.TrimPrefix(string(added.URI), "file://")
-		config := h.loadEffectiveConfig(h.globalConfig, addedPath, log)
-		h.configs[addedPath] = &config
+		h.configsMu.Unlock()
+		h.addConfigFolder(strings.TrimPrefix(string(added.URI), "file://"))
 	}
 
 	return nil
 }
 
 func (h *Handler) getConfigForDocument(uri protocol.DocumentURI) *Config {
+	config := h.lookupConfig(uri)
+
+	// WeChat mini-program stylesheets always get rpx conversion
+	if !config.Wxss && h.isWxssDocument(uri) {
+		wxssConfig := *config
+		wxssConfig.Wxss = true
+		return &wxssConfig
+	}
+	return config
+}
+
+// lookupConfig returns the config of the workspace folder containing uri,
+// falling back to the global config or defaults
+func (h *Handler) lookupConfig(uri protocol.DocumentURI) *Config {
 	docPath := strings.TrimPrefix(string(uri), "file://")
 
+	h.configsMu.RLock()
 	for folderPath, config := range h.configs {
 		if strings.HasPrefix(docPath, folderPath) {
+			h.configsMu.RUnlock()
 			return config
 		}
 	}
+	h.configsMu.RUnlock()
 
 	// If no project config found, return global config or default
 	if h.globalConfig != nil {
@@ -124,24 +237,61 @@ func (h *Handler) getConfigForDocument(uri protocol.DocumentURI) *Config {
 	return &defaultConfig
 }
 
+func (h *Handler) isWxssDocument(uri protocol.DocumentURI) bool {
+	if doc, ok := h.documents.get(uri); ok && doc.languageID == "wxss" {
+		return true
+	}
+	return strings.HasSuffix(string(uri), ".wxss")
+}
+
 func (h *Handler) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
-	uri := params.TextDocument.URI
-	lineCount := len(strings.Split(params.TextDocument.Text, "\n"))
+	item := params.TextDocument
+	doc := newDocument(item.LanguageID, item.Version, item.Text)
+
+	h.documents.open(item.URI, doc)
+	log.Sugar().Infof("Document opened: %s (version %d, %d lines, %d bytes)",
+		item.URI, item.Version, len(doc.Lines()), len(item.Text))
 
-	h.documents[uri] = strings.Split(params.TextDocument.Text, "\n")
-	log.Sugar().Infof("Document opened: %s (%d lines, %d bytes)",
-		uri, lineCount, len(params.TextDocument.Text))
+	h.publishDiagnostics(ctx, item.URI)
 	return nil
 }
 
+// DidChange applies changes decoded by the protocol package, which always
+// carry a range. The server routes textDocument/didChange to didChange
+// instead so full text replacements are recognised.
 func (h *Handler) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	changes := make([]contentChange, len(params.ContentChanges))
+	for i, change := range params.ContentChanges {
+		changes[i] = contentChange{Range: &change.Range, Text: change.Text}
+	}
+	return h.didChange(ctx, &didChangeParams{
+		TextDocument:   params.TextDocument,
+		ContentChanges: changes,
+	})
+}
+
+func (h *Handler) didChange(ctx context.Context, params *didChangeParams) error {
 	uri := params.TextDocument.URI
+	doc, err := h.documents.change(uri, params.TextDocument.Version, params.ContentChanges, h.getPositionEncoding())
+	if errors.Is(err, errDocumentNotOpen) {
+		log.Sugar().Warnf("Change for unknown document %s", uri)
+		return nil
+	}
+	if err != nil {
+		// the buffer is out of sync with the client, wait for it to reopen the document
+		log.Sugar().Errorf("Failed to apply changes to %s: %v", uri, err)
+		return fmt.Errorf("apply changes to %s: %w", uri, err)
+	}
+	log.Sugar().Debugf("Document changed: %s (version %d, %d content changes)",
+		uri, doc.version, len(params.ContentChanges))
 
-	if len(params.ContentChanges) > 0 {
-		h.documents[uri] = strings.Split(params.ContentChanges[0].Text, "\n")
-		log.Sugar().Debugf("Document changed: %s (%d content changes)",
-			uri, len(params.ContentChanges))
+	// the cursor follows the end of the last edit
+	if n := len(params.ContentChanges); n > 0 && params.ContentChanges[n-1].Range != nil {
+		last := params.ContentChanges[n-1]
+		h.trackCursor(uri, last.Range.Start.Line+uint32(strings.Count(last.Text, "\n")))
 	}
+
+	h.publishDiagnostics(ctx, uri)
 	return nil
 }
 
@@ -149,77 +299,160 @@ func (h *Handler) DidClose(ctx context.Context, params *protocol.DidCloseTextDoc
 	uri := params.TextDocument.URI
 
 	// Clean up document tracking when file is closed
-	delete(h.documents, uri)
+	h.documents.close(uri)
 	log.Sugar().Debugf("Document closed and cleaned up: %s", uri)
 
+	// clear the diagnostics of the closed document
+	h.publishDiagnostics(ctx, uri)
+
 	return nil
 }
 
 func (h *Handler) Completion(ctx context.Context, params *protocol.CompletionParams) (*protocol.CompletionList, error) {
 	uri := params.TextDocument.URI
-	line := h.documents[uri][params.Position.Line]
-	prefix := line[:params.Position.Character]
+	doc, line, err := h.documentLine(uri, params.Position)
+	if err != nil {
+		return nil, err
+	}
 
-	re := regexp.MustCompile(`(-?\d+(\.\d+)?)px`)
-	match := re.FindStringSubmatch(prefix)
-	if match == nil {
+	h.trackCursor(uri, params.Position.Line)
+	encoding := h.getPositionEncoding()
+	col := encoding.byteOffset(line, params.Position.Character)
+	config := h.getConfigForDocument(uri)
+
+	// a `14px..20px` range completes to a clamp() only, its second value
+	// isn't a size of its own
+	if items, ok := clampCompletions(doc, int(params.Position.Line), col, config, encoding); ok {
+		if err := h.checkModified(uri, doc); err != nil {
+			return nil, err
+		}
+		return &protocol.CompletionList{
+			IsIncomplete: false,
+			Items:        items,
+		}, nil
+	}
+
+	match, ok := unitBefore(doc, int(params.Position.Line), col)
+	if !ok {
 		return &protocol.CompletionList{
 			IsIncomplete: false,
 			Items:        []protocol.CompletionItem{},
 		}, nil
 	}
 
-	pxValueStr := match[1]
-	pxValue, err := strconv.ParseFloat(pxValueStr, 64)
+	converter := config.converter()
+	items := []protocol.CompletionItem{}
+	for _, conv := range converter.Conversions(match) {
+		log.Sugar().Debugf("Conversion completed: %s → %s (viewport: %.0f)",
+			match.Text(), conv.Text(), converter.ViewportWidth(match))
+
+		items = append(items, protocol.CompletionItem{
+			Kind:       protocol.CompletionItemKindUnit,
+			Label:      conv.Text(),
+			FilterText: match.Text(),
+			TextEdit: &protocol.TextEdit{
+				Range:   encoding.lineRange(params.Position.Line, line, match.Start, match.End),
+				NewText: conv.Text(),
+			},
+		})
+	}
+
+	if err := h.checkModified(uri, doc); err != nil {
+		return nil, err
+	}
+	return &protocol.CompletionList{
+		IsIncomplete: false,
+		Items:        items,
+	}, nil
+}
+
+func (h *Handler) Hover(ctx context.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
+	uri := params.TextDocument.URI
+	doc, line, err := h.documentLine(uri, params.Position)
 	if err != nil {
-		log.Sugar().Warnf("Failed to parse px value '%s' in %s:%d: %v",
-			pxValueStr, uri, params.Position.Line, err)
-		return nil, fmt.Errorf("failed to parse px value: %v", err)
+		return nil, err
 	}
 
 	config := h.getConfigForDocument(uri)
-	vwValue := (pxValue / float64(config.ViewportWidth)) * 100
-	vwValueStr := strconv.FormatFloat(vwValue, 'f', config.UnitPrecision, 64)
+	if !hoverEnabled(config, line) {
+		return nil, nil
+	}
 
-	// Calculate the range to replace more robustly
-	expectedReplaceText := pxValueStr + "px"
-	textBefore := prefix[:len(prefix)-len(expectedReplaceText)]
+	encoding := h.getPositionEncoding()
+	match, ok := unitAt(doc, int(params.Position.Line), encoding.byteOffset(line, params.Position.Character))
+	if !ok {
+		return nil, nil
+	}
 
-	log.Sugar().Debugf("Completion debug: prefix='%s', pxValueStr='%s', expectedReplace='%s', textBefore='%s'",
-		prefix, pxValueStr, expectedReplaceText, textBefore)
+	conversions := hoverConversions(match, config)
+	if len(conversions) == 0 {
+		return nil, nil
+	}
+	if err := h.checkModified(uri, doc); err != nil {
+		return nil, err
+	}
+	hoverRange := encoding.lineRange(params.Position.Line, line, match.Start, match.End)
+	log.Sugar().Debugf("Hover: %s → %d conversions (viewport: %.0f)",
+		match.Text(), len(conversions), config.converter().ViewportWidth(match))
+
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: hoverMarkdown(match, conversions, config),
+		},
+		Range: &hoverRange,
+	}, nil
+}
+
+// hoverEnabled reports whether the cssrem hover settings allow a hover on line
+func hoverEnabled(config *Config, line string) bool {
+	switch config.Hover {
+	case SchemaJsonHoverDisabled:
+		return false
+	case SchemaJsonHoverOnlyMark:
+		// like cssrem, only lines carrying a `/* ... */` mark get a hover
+		if !strings.Contains(line, "/*") {
+			return false
+		}
+	}
+	return config.VwHover || config.RemHover || config.Wxss
+}
 
-	// Validate that the text to replace matches our expectation
-	if len(prefix) < len(expectedReplaceText) ||
-		prefix[len(prefix)-len(expectedReplaceText):] != expectedReplaceText {
-		log.Sugar().Warnf("Unexpected text structure for completion in %s:%d. Expected '%s' at end of '%s'",
-			uri, params.Position.Line, expectedReplaceText, prefix)
-		return nil, fmt.Errorf("invalid text structure for completion")
+// hoverConversions filters the conversions of match by the vwHover and remHover settings
+func hoverConversions(match convert.Match, config *Config) []convert.Conversion {
+	var conversions []convert.Conversion
+	for _, conv := range config.converter().Conversions(match) {
+		if conv.Involves("vw") && !config.VwHover {
+			continue
+		}
+		if conv.Involves("rem") && !config.RemHover {
+			continue
+		}
+		conversions = append(conversions, conv)
 	}
+	return conversions
+}
 
-	log.Sugar().Debugf("Conversion completed: %spx → %svw (viewport: %.0f)",
-		pxValueStr, vwValueStr, config.ViewportWidth)
+func hoverMarkdown(match convert.Match, conversions []convert.Conversion, config *Config) string {
+	source := "built-in defaults"
+	if config.Source != "" {
+		source = "`" + config.Source + "`"
+	}
 
-	return &protocol.CompletionList{
-		IsIncomplete: false,
-		Items: []protocol.CompletionItem{
-			{
-				Kind:       protocol.CompletionItemKindUnit,
-				Label:      vwValueStr + "vw",
-				FilterText: pxValueStr + "px",
-				TextEdit: &protocol.TextEdit{
-					Range: protocol.Range{
-						Start: protocol.Position{
-							Line:      params.Position.Line,
-							Character: uint32(len(textBefore)),
-						},
-						End: protocol.Position{
-							Line:      params.Position.Line,
-							Character: params.Position.Character,
-						},
-					},
-					NewText: vwValueStr + "vw",
-				},
-			},
-		},
-	}, nil
+	converter := config.converter()
+	var b strings.Builder
+	for _, conv := range conversions {
+		fmt.Fprintf(&b, "**%s → %s**\n\n", conv.From.Text(), conv.Text())
+		fmt.Fprintf(&b, "```css\n%s\n```\n\n", converter.Replacement(conv))
+	}
+	viewportWidth := converter.ViewportWidth(match)
+	if viewportWidth != config.ViewportWidth {
+		fmt.Fprintf(&b, "- viewport width: %spx (`@media %s`)\n", strconv.FormatFloat(viewportWidth, 'f', -1, 64), match.Media)
+	} else {
+		fmt.Fprintf(&b, "- viewport width: %spx\n", strconv.FormatFloat(viewportWidth, 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, "- root font size: %spx\n", strconv.FormatFloat(config.RootFontSize, 'f', -1, 64))
+	fmt.Fprintf(&b, "- precision: %d\n", config.UnitPrecision)
+	fmt.Fprintf(&b, "- config: %s\n", source)
+	return b.String()
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+
+	"go.lsp.dev/protocol"
+)
+
+// inlay hints are LSP 3.17, which the protocol package predates
+const (
+	methodTextDocumentInlayHint     = "textDocument/inlayHint"
+	methodWorkspaceInlayHintRefresh = "workspace/inlayHint/refresh"
+)
+
+type inlayHintParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Range        protocol.Range                  `json:"range"`
+}
+
+type inlayHint struct {
+	Position    protocol.Position `json:"position"`
+	Label       string            `json:"label"`
+	PaddingLeft bool              `json:"paddingLeft,omitempty"`
+}
+
+// inlayHints shows the vw value after each px literal and the px value
+// after each vw literal in the requested range. With the cssrem
+// `currentLine` setting on "show", only the cursor line gets hints.
+func (h *Handler) inlayHints(ctx context.Context, params *inlayHintParams) ([]inlayHint, error) {
+	uri := params.TextDocument.URI
+	doc, err := h.openDocument(uri)
+	if err != nil {
+		return nil, err
+	}
+	lines := doc.Lines()
+	rng := params.Range
+	if rng.Start.Line > rng.End.Line || int(rng.Start.Line) >= len(lines) {
+		return nil, errInvalidParams("invalid range %v in %s of %d lines", rng, uri, len(lines))
+	}
+
+	config := h.getConfigForDocument(uri)
+	if config.CurrentLine == SchemaJsonCurrentLineShow {
+		cursor, ok := h.documents.cursor(uri)
+		if !ok || cursor < rng.Start.Line || cursor > rng.End.Line {
+			return []inlayHint{}, nil
+		}
+		rng = protocol.Range{Start: protocol.Position{Line: cursor}, End: protocol.Position{Line: cursor}}
+	}
+
+	converter := config.converter()
+	encoding := h.getPositionEncoding()
+	scan, index := doc.Scan()
+	hints := []inlayHint{}
+	for _, match := range scan.Matches {
+		i, match := index.lineMatch(match)
+		lineNum := uint32(i)
+		if lineNum < rng.Start.Line || lineNum > rng.End.Line {
+			continue
+		}
+
+		var label string
+		switch {
+		case converter.Excluded(match):
+			continue
+		case match.Unit == "px" && !converter.Ignored(match):
+			label = "→ " + converter.PxToVwConversion(match).Text()
+		case match.Unit == "vw":
+			label = "= " + converter.VwToPxConversion(match).Text()
+		default:
+			continue
+		}
+		hints = append(hints, inlayHint{
+			Position:    protocol.Position{Line: lineNum, Character: encoding.character(lines[i], match.End)},
+			Label:       label,
+			PaddingLeft: true,
+		})
+	}
+
+	if err := h.checkModified(uri, doc); err != nil {
+		return nil, err
+	}
+	log.Sugar().Debugf("Inlay hints for %s in %v: %d", uri, params.Range, len(hints))
+	return hints, nil
+}
+
+// trackCursor records the cursor line of a document and, when hints only
+// follow the cursor line, asks the client to refresh them after it moved
+func (h *Handler) trackCursor(uri protocol.DocumentURI, line uint32) {
+	if !h.documents.moveCursor(uri, line) {
+		return
+	}
+	h.stateMu.RLock()
+	refresh := h.inlayHintRefresh
+	h.stateMu.RUnlock()
+	if !refresh || h.conn == nil || h.getConfigForDocument(uri).CurrentLine != SchemaJsonCurrentLineShow {
+		return
+	}
+
+	// notifications run on the connection's reader, which a call must not block
+	go func() {
+		if _, err := h.conn.Call(h.ctx, methodWorkspaceInlayHintRefresh, nil, nil); err != nil {
+			log.Sugar().Debugf("Inlay hint refresh of %s failed: %v", uri, err)
+		}
+	}()
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestInlayHints(t *testing.T) {
+	text := ".card {\n  width: 144px;\n  border: 1px solid;\n  /* é */ margin: 10vw;\n}"
+	whole := protocol.Range{End: protocol.Position{Line: 4}}
+
+	tests := []struct {
+		name        string
+		currentLine SchemaJsonCurrentLine
+		// cursor is the tracked cursor line, -1 when unknown
+		cursor   int
+		rng      protocol.Range
+		expected []inlayHint
+	}{
+		{
+			name:        "All lines",
+			currentLine: SchemaJsonCurrentLineDisabled,
+			cursor:      -1,
+			rng:         whole,
+			expected: []inlayHint{
+				{Position: protocol.Position{Line: 1, Character: 14}, Label: "→ 10.000vw", PaddingLeft: true},
+				// columns count UTF-16 code units
+				{Position: protocol.Position{Line: 3, Character: 22}, Label: "= 144px", PaddingLeft: true},
+			},
+		},
+		{
+			name:        "Range",
+			currentLine: SchemaJsonCurrentLineDisabled,
+			cursor:      -1,
+			rng:         protocol.Range{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 3}},
+			expected: []inlayHint{
+				{Position: protocol.Position{Line: 3, Character: 22}, Label: "= 144px", PaddingLeft: true},
+			},
+		},
+		{
+			name:        "Cursor line",
+			currentLine: SchemaJsonCurrentLineShow,
+			cursor:      1,
+			rng:         whole,
+			expected: []inlayHint{
+				{Position: protocol.Position{Line: 1, Character: 14}, Label: "→ 10.000vw", PaddingLeft: true},
+			},
+		},
+		{
+			name:        "Cursor outside the range",
+			currentLine: SchemaJsonCurrentLineShow,
+			cursor:      1,
+			rng:         protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 4}},
+		},
+		{
+			name:        "Cursor unknown",
+			currentLine: SchemaJsonCurrentLineShow,
+			cursor:      -1,
+			rng:         whole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, uri := newTestHandler(t, text, Config{
+				ViewportWidth:     1440,
+				UnitPrecision:     3,
+				IgnoresViaCommand: []string{"1px"},
+				CurrentLine:       tt.currentLine,
+			})
+			if tt.cursor >= 0 {
+				handler.trackCursor(uri, uint32(tt.cursor))
+			}
+
+			hints, err := handler.inlayHints(context.Background(), &inlayHintParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Range:        tt.rng,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(hints) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, hints)
+			}
+			for i := range hints {
+				if hints[i] != tt.expected[i] {
+					t.Errorf("Hint %d: expected %v, got %v", i, tt.expected[i], hints[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInlayHintsBreakpoint(t *testing.T) {
+	text := "@media (max-width: 768px) {\n  .a { width: 20vw; margin: 75px; }\n}"
+	handler, uri := newTestHandler(t, text, Config{
+		ViewportWidth: 1440,
+		UnitPrecision: 3,
+		Breakpoints:   []convert.Breakpoint{{Media: "(max-width: 768px)", ViewportWidth: 375}},
+		CurrentLine:   SchemaJsonCurrentLineDisabled,
+	})
+
+	hints, err := handler.inlayHints(context.Background(), &inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        protocol.Range{End: protocol.Position{Line: 2}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// both directions use the breakpoint width, like hover
+	expected := []inlayHint{
+		{Position: protocol.Position{Line: 1, Character: 18}, Label: "= 75px", PaddingLeft: true},
+		{Position: protocol.Position{Line: 1, Character: 32}, Label: "→ 20.000vw", PaddingLeft: true},
+	}
+	if len(hints) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, hints)
+	}
+	for i := range hints {
+		if hints[i] != expected[i] {
+			t.Errorf("Hint %d: expected %v, got %v", i, expected[i], hints[i])
+		}
+	}
+}
+
+func TestInlayHintRefresh(t *testing.T) {
+	conn := &recordingConn{}
+	handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+	handler.inlayHintRefresh = true
+	handler.configs["/project"] = &Config{ViewportWidth: 1440, UnitPrecision: 3, CurrentLine: SchemaJsonCurrentLineShow}
+	uri := protocol.DocumentURI("file:///project/style.css")
+	handler.documents.open(uri, newDocument("css", 1, "a {\n  width: 144px;\n}"))
+
+	// typing a newline moves the cursor to the next line
+	err := handler.didChange(context.Background(), &didChangeParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			Version:                2,
+		},
+		ContentChanges: []contentChange{{Range: rangeOf(1, 15, 1, 15), Text: "\n  "}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor, ok := handler.documents.cursor(uri); !ok || cursor != 2 {
+		t.Errorf("Expected the cursor on line 2, got %d (%v)", cursor, ok)
+	}
+	// staying on the line doesn't refresh again
+	handler.trackCursor(uri, 2)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && len(conn.sent(methodWorkspaceInlayHintRefresh)) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if refreshes := conn.sent(methodWorkspaceInlayHintRefresh); len(refreshes) != 1 {
+		t.Errorf("Expected 1 refresh, got %d", len(refreshes))
+	}
+}
+
+func TestInlayHintRequest(t *testing.T) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	conn := newTestConn(t, handler)
+	if err := conn.call(protocol.MethodInitialize, &protocol.InitializeParams{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the request is decoded by the server rather than the protocol package
+	err := conn.call(methodTextDocumentInlayHint, &inlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///project/style.css"},
+	})
+	if code := errorCode(err); code != jsonrpc2.InvalidParams {
+		t.Errorf("Expected InvalidParams for a closed document, got %v", err)
+	}
+}
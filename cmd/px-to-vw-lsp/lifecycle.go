@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// serverState is the stage of the LSP lifecycle the server is in
+type serverState int
+
+const (
+	stateUninitialized serverState = iota
+	stateRunning
+	stateShutdown
+	stateExited
+)
+
+var (
+	errServerNotInitialized = jsonrpc2.NewError(jsonrpc2.ServerNotInitialized, "server not initialized")
+	errAlreadyInitialized   = jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server already initialized")
+	errShuttingDown         = jsonrpc2.NewError(jsonrpc2.InvalidRequest, "server is shutting down")
+)
+
+// lifecycleHandler rejects calls made before initialize with
+// ServerNotInitialized and calls made after shutdown with InvalidRequest.
+// Notifications other than exit are dropped in both cases.
+func lifecycleHandler(handler *Handler, next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if req.Method() == protocol.MethodInitialize || req.Method() == protocol.MethodExit {
+			return next(ctx, reply, req)
+		}
+
+		state := handler.getState()
+		if state == stateRunning {
+			return next(ctx, reply, req)
+		}
+
+		if _, isCall := req.(*jsonrpc2.Call); !isCall {
+			log.Sugar().Debugf("Dropped %s received in state %d", req.Method(), state)
+			return reply(ctx, nil, nil)
+		}
+		if state == stateUninitialized {
+			return reply(ctx, nil, errServerNotInitialized)
+		}
+		return reply(ctx, nil, errShuttingDown)
+	}
+}
+
+// cancelRequestHandler cancels the context of a call on $/cancelRequest
+func cancelRequestHandler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	next, cancel := jsonrpc2.CancelHandler(next)
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if req.Method() != protocol.MethodCancelRequest {
+			return next(ctx, reply, req)
+		}
+
+		var params struct {
+			ID jsonrpc2.ID `json:"id"`
+		}
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+		}
+		log.Sugar().Debugf("Cancelling request %v", params.ID)
+		cancel(params.ID)
+		return reply(ctx, nil, nil)
+	}
+}
+
+func (h *Handler) getState() serverState {
+	h.stateMu.RLock()
+	defer h.stateMu.RUnlock()
+	return h.state
+}
+
+// ExitCode returns the status the process should exit with: 0 after a
+// shutdown followed by exit, 1 otherwise
+func (h *Handler) ExitCode() int {
+	h.stateMu.RLock()
+	defer h.stateMu.RUnlock()
+	return h.exitCode
+}
+
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.stateMu.Lock()
+	h.state = stateShutdown
+	h.stateMu.Unlock()
+	log.Sugar().Info("Shutting down")
+
+	// stop background work and the config watchers
+	h.cancel()
+	h.Close()
+	if h.globalConfig != nil {
+		h.globalConfig.Close()
+	}
+	return nil
+}
+
+func (h *Handler) Exit(ctx context.Context) error {
+	h.stateMu.Lock()
+	if h.state == stateShutdown {
+		h.exitCode = 0
+	}
+	h.state = stateExited
+	h.stateMu.Unlock()
+	log.Sugar().Infof("Exiting with status %d", h.ExitCode())
+
+	h.cancel()
+	if h.conn != nil {
+		return h.conn.Close()
+	}
+	return nil
+}
+
+func (h *Handler) WorkDoneProgressCancel(ctx context.Context, params *protocol.WorkDoneProgressCancelParams) error {
+	h.stateMu.Lock()
+	cancel, ok := h.progressCancels[params.Token.String()]
+	h.stateMu.Unlock()
+
+	if ok {
+		log.Sugar().Infof("Cancelling work done progress %s", params.Token)
+		cancel()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// testConn sends messages through the connection handler of a handler and
+// collects the replies
+type testConn struct {
+	t       *testing.T
+	handler jsonrpc2.Handler
+	nextID  int32
+}
+
+func newTestConn(t *testing.T, handler *Handler) *testConn {
+	return &testConn{t: t, handler: connHandler(handler)}
+}
+
+// call sends a request and waits for its response
+func (c *testConn) call(method string, params interface{}) error {
+	c.t.Helper()
+	c.nextID++
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(c.nextID), method, params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	replies := make(chan error, 1)
+	err = c.handler(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+		replies <- err
+		return nil
+	}, req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	select {
+	case err := <-replies:
+		return err
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("No response to %s", method)
+		return nil
+	}
+}
+
+func (c *testConn) notify(method string, params interface{}) {
+	c.t.Helper()
+	req, err := jsonrpc2.NewNotification(method, params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	noReply := func(ctx context.Context, result interface{}, err error) error { return nil }
+	if err := c.handler(context.Background(), noReply, req); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	conn := newTestConn(t, handler)
+	uri := protocol.DocumentURI("file:///project/style.css")
+	hover := &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	}}
+
+	// before initialize
+	if code := errorCode(conn.call(protocol.MethodTextDocumentHover, hover)); code != jsonrpc2.ServerNotInitialized {
+		t.Errorf("Expected ServerNotInitialized before initialize, got %d", code)
+	}
+	conn.notify(protocol.MethodTextDocumentDidOpen, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Text: ".a { width: 10px; }"},
+	})
+	if _, ok := handler.documents.get(uri); ok {
+		t.Error("Expected didOpen before initialize to be dropped")
+	}
+
+	if err := conn.call(protocol.MethodInitialize, &protocol.InitializeParams{}); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if code := errorCode(conn.call(protocol.MethodInitialize, &protocol.InitializeParams{})); code != jsonrpc2.InvalidRequest {
+		t.Errorf("Expected InvalidRequest on a second initialize, got %d", code)
+	}
+
+	conn.notify(protocol.MethodTextDocumentDidOpen, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Text: ".a { width: 10px; }"},
+	})
+	if err := conn.call(protocol.MethodTextDocumentHover, hover); err != nil {
+		t.Errorf("Unexpected hover error: %v", err)
+	}
+
+	if err := conn.call(protocol.MethodShutdown, nil); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if handler.ctx.Err() == nil {
+		t.Error("Expected background work to be cancelled on shutdown")
+	}
+	if code := errorCode(conn.call(protocol.MethodTextDocumentHover, hover)); code != jsonrpc2.InvalidRequest {
+		t.Errorf("Expected InvalidRequest after shutdown, got %d", code)
+	}
+
+	conn.notify(protocol.MethodExit, nil)
+	if code := handler.ExitCode(); code != 0 {
+		t.Errorf("Expected exit code 0 after shutdown, got %d", code)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	conn := newTestConn(t, handler)
+
+	if err := conn.call(protocol.MethodInitialize, &protocol.InitializeParams{}); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	conn.notify(protocol.MethodExit, nil)
+	if code := handler.ExitCode(); code != 1 {
+		t.Errorf("Expected exit code 1 without shutdown, got %d", code)
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	log = createTestLogger(t)
+	started := make(chan struct{})
+	h := cancelRequestHandler(concurrentHandler(safeHandler(
+		func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+			close(started)
+			<-ctx.Done()
+			return reply(ctx, nil, ctx.Err())
+		},
+	)))
+
+	call, err := jsonrpc2.NewCall(jsonrpc2.NewStringID("slow"), protocol.MethodTextDocumentCompletion, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replies := make(chan error, 1)
+	err = h(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+		replies <- err
+		return nil
+	}, call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	cancel, err := jsonrpc2.NewNotification(protocol.MethodCancelRequest, json.RawMessage(`{"id":"slow"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h(context.Background(), func(context.Context, interface{}, error) error { return nil }, cancel); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-replies:
+		if code := errorCode(err); code != protocol.CodeRequestCancelled {
+			t.Errorf("Expected RequestCancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancelled request never replied")
+	}
+}
+
+func TestWorkDoneProgressCancel(t *testing.T) {
+	conn := &recordingConn{}
+	handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	token := protocol.NewProgressToken("convert")
+	progress := handler.startProgress(ctx, token, "Converting", cancel)
+
+	if err := handler.WorkDoneProgressCancel(context.Background(), &protocol.WorkDoneProgressCancelParams{Token: *token}); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the progress context to be cancelled")
+	}
+
+	progress.end(context.Background(), "Cancelled")
+	if len(handler.progressCancels) != 0 {
+		t.Errorf("Expected the cancel func to be forgotten, got %d", len(handler.progressCancels))
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
-	"regexp"
-	"strconv"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/meow-d/px-to-vw-lsp/pkg/convert"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
 )
 
 func TestRegexEdgeCases(t *testing.T) {
@@ -14,10 +20,10 @@ func TestRegexEdgeCases(t *testing.T) {
 		expectedPx  string
 	}{
 		{
+			// a breakpoint, not a size
 			name:        "Media query with spaces",
 			input:       "@media (min-width: 1536px",
-			expectMatch: true,
-			expectedPx:  "1536",
+			expectMatch: false,
 		},
 		{
 			name:        "Property value at end of string",
@@ -70,11 +76,18 @@ func TestRegexEdgeCases(t *testing.T) {
 		},
 	}
 
-	re := regexp.MustCompile(`(-?\d+(\.\d+)?)px`)
-
+	converter, err := convert.New(convert.Config{ViewportWidth: 1440, UnitPrecision: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			matches := re.FindAllStringSubmatch(tt.input, -1)
+			var matches []convert.Match
+			for _, match := range convert.FindUnits(tt.input) {
+				if match.Unit == "px" && !converter.Excluded(match) {
+					matches = append(matches, match)
+				}
+			}
 
 			if !tt.expectMatch {
 				if matches != nil {
@@ -90,8 +103,8 @@ func TestRegexEdgeCases(t *testing.T) {
 
 			// Take the last match
 			match := matches[len(matches)-1]
-			if match[1] != tt.expectedPx {
-				t.Errorf("Expected px value %q, got %q", tt.expectedPx, match[1])
+			if match.Number != tt.expectedPx {
+				t.Errorf("Expected px value %q, got %q", tt.expectedPx, match.Number)
 			}
 		})
 	}
@@ -134,27 +147,25 @@ func TestCursorPositionScenarios(t *testing.T) {
 		},
 	}
 
-	re := regexp.MustCompile(`(-?\d+(\.\d+)?)px`)
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			prefix := tt.line[:tt.cursorPos]
-			match := re.FindStringSubmatch(prefix)
+			match, ok := convert.UnitBefore(tt.line, tt.cursorPos)
 
 			if !tt.expectedMatch {
-				if match != nil {
+				if ok {
 					t.Errorf("Expected no match, got %v", match)
 				}
 				return
 			}
 
-			if match == nil {
+			if !ok {
 				t.Errorf("Expected match for prefix %q, got nil", prefix)
 				return
 			}
 
-			if match[1] != tt.expectedPx {
-				t.Errorf("Expected px value %q, got %q", tt.expectedPx, match[1])
+			if match.Number != tt.expectedPx {
+				t.Errorf("Expected px value %q, got %q", tt.expectedPx, match.Number)
 			}
 
 			if tt.expectedPrefix != "" && prefix != tt.expectedPrefix {
@@ -217,9 +228,7 @@ func TestConfigIntegration(t *testing.T) {
 				UnitPrecision: tt.precision,
 			}
 
-			// Simulate conversion logic
-			vwValue := (tt.pxValue / config.ViewportWidth) * 100
-			result := formatFloat(vwValue, config.UnitPrecision)
+			result := config.converter().PxToVw(tt.pxValue)
 
 			if result != tt.expectedVw {
 				t.Errorf("Conversion: %fpx at %.0f viewport with precision %d = %svw (expected %svw)",
@@ -229,7 +238,390 @@ func TestConfigIntegration(t *testing.T) {
 	}
 }
 
-// formatFloat helper function for testing
-func formatFloat(f float64, precision int) string {
-	return strconv.FormatFloat(f, 'f', precision, 64)
+func TestHover(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		character uint32
+		config    Config
+		expect    string
+	}{
+		{
+			name:      "Cursor inside px value",
+			line:      "  width: 348px;",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverAlways, VwHover: true},
+			expect:    "24.167vw",
+		},
+		{
+			name:      "Cursor right after px value",
+			line:      "  margin: 10px 20px;",
+			character: 19,
+			config:    Config{ViewportWidth: 1920, UnitPrecision: 2, Hover: SchemaJsonHoverAlways, VwHover: true},
+			expect:    "1.04vw",
+		},
+		{
+			name:      "Cursor away from px value",
+			line:      "  width: 348px;",
+			character: 3,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverAlways, VwHover: true},
+		},
+		{
+			name:      "Hover disabled",
+			line:      "  width: 348px;",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverDisabled, VwHover: true},
+		},
+		{
+			name:      "vwHover disabled",
+			line:      "  width: 348px;",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverAlways, VwHover: false},
+		},
+		{
+			name:      "onlyMark without mark",
+			line:      "  width: 348px;",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverOnlyMark, VwHover: true},
+		},
+		{
+			name:      "vw value with vw enabled",
+			line:      "  width: 12.5vw;",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverAlways, VwHover: true, Vw: true},
+			expect:    "180px",
+		},
+		{
+			name:      "vw value with vw disabled",
+			line:      "  width: 12.5vw;",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverAlways, VwHover: true},
+		},
+		{
+			name:      "rem value with remHover",
+			line:      "  font-size: 1.5rem;",
+			character: 15,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, RootFontSize: 16, Hover: SchemaJsonHoverAlways, RemHover: true},
+			expect:    "24px",
+		},
+		{
+			name:      "rem value without remHover",
+			line:      "  font-size: 1.5rem;",
+			character: 15,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, RootFontSize: 16, Hover: SchemaJsonHoverAlways, VwHover: true},
+		},
+		{
+			name:      "onlyMark with mark",
+			line:      "  width: 348px; /* sidebar */",
+			character: 11,
+			config:    Config{ViewportWidth: 1440, UnitPrecision: 3, Hover: SchemaJsonHoverOnlyMark, VwHover: true},
+			expect:    "24.167vw",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, uri := newTestHandler(t, tt.line, tt.config)
+
+			hover, err := handler.Hover(context.Background(), &protocol.HoverParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+					Position:     protocol.Position{Line: 0, Character: tt.character},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.expect == "" {
+				if hover != nil {
+					t.Errorf("Expected no hover, got %q", hover.Contents.Value)
+				}
+				return
+			}
+
+			if hover == nil {
+				t.Fatalf("Expected hover containing %q, got nil", tt.expect)
+			}
+			if !strings.Contains(hover.Contents.Value, tt.expect) {
+				t.Errorf("Expected hover containing %q, got %q", tt.expect, hover.Contents.Value)
+			}
+		})
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		config        Config
+		expectLabels  []string
+		expectedStart uint32
+	}{
+		{
+			name:          "px to vw",
+			line:          "  width: 348px",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels:  []string{"24.167vw"},
+			expectedStart: 9,
+		},
+		{
+			name:          "Last of multiple px values",
+			line:          "  margin: 10px 20px",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels:  []string{"1.389vw"},
+			expectedStart: 15,
+		},
+		{
+			name:          "vw to px when enabled",
+			line:          "  width: 12.5vw",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, Vw: true},
+			expectLabels:  []string{"180px"},
+			expectedStart: 9,
+		},
+		{
+			name:          "px to vw and rem",
+			line:          "  font-size: 24px",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, RootFontSize: 16},
+			expectLabels:  []string{"1.667vw", "1.5rem"},
+			expectedStart: 13,
+		},
+		{
+			name:          "rem to px",
+			line:          "  font-size: 1.5rem",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, RootFontSize: 16},
+			expectLabels:  []string{"24px"},
+			expectedStart: 13,
+		},
+		{
+			name:          "px to rpx in wxss mode",
+			line:          "  width: 24px",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, Wxss: true, WxssDeviceWidth: 375, WxssScreenWidth: 750},
+			expectLabels:  []string{"48rpx", "1.667vw"},
+			expectedStart: 9,
+		},
+		{
+			name:          "rpx to px in wxss mode",
+			line:          "  width: 48rpx",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, Wxss: true, WxssDeviceWidth: 375, WxssScreenWidth: 750},
+			expectLabels:  []string{"24px"},
+			expectedStart: 9,
+		},
+		{
+			name:         "rpx without wxss mode",
+			line:         "  width: 48rpx",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3, WxssDeviceWidth: 375, WxssScreenWidth: 750},
+			expectLabels: []string{},
+		},
+		{
+			name:         "vw to px when disabled",
+			line:         "  width: 12.5vw",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels: []string{},
+		},
+		{
+			name:         "No unit before cursor",
+			line:         "  width: 100%",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels: []string{},
+		},
+		{
+			name:         "Inside a comment",
+			line:         "  /* width: 348px",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels: []string{},
+		},
+		{
+			name:         "Class name",
+			line:         "  .mt-10px",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels: []string{},
+		},
+		{
+			name:         "Media query condition",
+			line:         "@media (min-width: 1536px",
+			config:       Config{ViewportWidth: 1440, UnitPrecision: 3},
+			expectLabels: []string{},
+		},
+		{
+			name:          "Media query condition with convertConditions",
+			line:          "@media (min-width: 1536px",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, ConvertConditions: true},
+			expectLabels:  []string{"106.667vw"},
+			expectedStart: 19,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, uri := newTestHandler(t, tt.line, tt.config)
+
+			list, err := handler.Completion(context.Background(), &protocol.CompletionParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+					Position:     protocol.Position{Line: 0, Character: uint32(len(tt.line))},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(list.Items) != len(tt.expectLabels) {
+				t.Fatalf("Expected %d items, got %d", len(tt.expectLabels), len(list.Items))
+			}
+			for i, label := range tt.expectLabels {
+				item := list.Items[i]
+				if item.Label != label {
+					t.Errorf("Item %d label: got %q, want %q", i, item.Label, label)
+				}
+				if item.TextEdit.Range.Start.Character != tt.expectedStart {
+					t.Errorf("Item %d range start: got %d, want %d", i, item.TextEdit.Range.Start.Character, tt.expectedStart)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakpointViewport(t *testing.T) {
+	text := ".a { width: 75px }\n@media (max-width: 768px) {\n  .a { width: 75px }\n}"
+	config := Config{
+		ViewportWidth: 1440,
+		UnitPrecision: 3,
+		Hover:         SchemaJsonHoverAlways,
+		VwHover:       true,
+		Breakpoints:   []convert.Breakpoint{{Media: "(max-width: 768px)", ViewportWidth: 375}},
+	}
+	handler, uri := newTestHandler(t, text, config)
+
+	tests := []struct {
+		name     string
+		position protocol.Position
+		expected string
+	}{
+		{"Outside the media block", protocol.Position{Line: 0, Character: 16}, "5.208vw"},
+		{"Inside the media block", protocol.Position{Line: 2, Character: 18}, "20.000vw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position := protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     tt.position,
+			}
+			list, err := handler.Completion(context.Background(), &protocol.CompletionParams{TextDocumentPositionParams: position})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(list.Items) != 1 || list.Items[0].Label != tt.expected {
+				t.Errorf("Expected completion %q, got %+v", tt.expected, list.Items)
+			}
+
+			hover, err := handler.Hover(context.Background(), &protocol.HoverParams{TextDocumentPositionParams: position})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if hover == nil || !strings.Contains(hover.Contents.Value, tt.expected) {
+				t.Errorf("Expected hover containing %q, got %+v", tt.expected, hover)
+			}
+		})
+	}
+}
+
+func TestWxssAutoEnable(t *testing.T) {
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, WxssDeviceWidth: 375, WxssScreenWidth: 750}
+
+	tests := []struct {
+		name       string
+		uri        protocol.DocumentURI
+		languageID protocol.LanguageIdentifier
+		expectWxss bool
+	}{
+		{name: "wxss extension", uri: "file:///project/app.wxss", languageID: "css", expectWxss: true},
+		{name: "wxss language id", uri: "file:///project/app.css", languageID: "wxss", expectWxss: true},
+		{name: "plain css", uri: "file:///project/app.css", languageID: "css", expectWxss: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestHandler(t, "", config)
+			err := handler.DidOpen(context.Background(), &protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{URI: tt.uri, LanguageID: tt.languageID, Text: "width: 24px"},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := handler.getConfigForDocument(tt.uri).Wxss; got != tt.expectWxss {
+				t.Errorf("Wxss: got %v, want %v", got, tt.expectWxss)
+			}
+			if handler.configs["/project"].Wxss {
+				t.Errorf("Workspace config should not be modified")
+			}
+		})
+	}
+}
+
+// newTestHandler returns a handler with a single document under a project using config
+func newTestHandler(t *testing.T, text string, config Config) (*Handler, protocol.DocumentURI) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	uri := protocol.DocumentURI("file:///project/style.css")
+	handler.documents.open(uri, newDocument("css", 1, text))
+	handler.configs["/project"] = &config
+	return handler, uri
+}
+
+// recordingConn is a jsonrpc2.Conn that records the messages sent to the client
+// and answers calls from results, or with errors from errs
+type recordingConn struct {
+	mu       sync.Mutex
+	messages []recordedMessage
+	results  map[string]interface{}
+	errs     map[string]error
+}
+
+type recordedMessage struct {
+	method string
+	params interface{}
+}
+
+func (c *recordingConn) Call(ctx context.Context, method string, params, result interface{}) (jsonrpc2.ID, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, recordedMessage{method: method, params: params})
+
+	if err := c.errs[method]; err != nil {
+		return jsonrpc2.NewNumberID(0), err
+	}
+	if response, ok := c.results[method]; ok && result != nil {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return jsonrpc2.NewNumberID(0), err
+		}
+		return jsonrpc2.NewNumberID(0), json.Unmarshal(data, result)
+	}
+	return jsonrpc2.NewNumberID(0), nil
+}
+
+func (c *recordingConn) Notify(ctx context.Context, method string, params interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, recordedMessage{method: method, params: params})
+	return nil
+}
+
+func (c *recordingConn) Go(ctx context.Context, handler jsonrpc2.Handler) {}
+func (c *recordingConn) Close() error                                     { return nil }
+func (c *recordingConn) Done() <-chan struct{}                            { return nil }
+func (c *recordingConn) Err() error                                       { return nil }
+
+// sent returns the recorded messages with the given method
+func (c *recordingConn) sent(method string) []recordedMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var messages []recordedMessage
+	for _, message := range c.messages {
+		if message.method == method {
+			messages = append(messages, message)
+		}
+	}
+	return messages
 }
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"os"
 )
 
 func parseFlags() (logLevel, logFile string) {
@@ -16,7 +17,10 @@ func parseFlags() (logLevel, logFile string) {
 func initLogger(logLevel, logFile string) *zap.Logger {
 	cfg := zap.NewDevelopmentConfig()
 	cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	cfg.OutputPaths = []string{"stderr", logFile}
+	cfg.OutputPaths = []string{"stderr"}
+	if logFile != "" {
+		cfg.OutputPaths = append(cfg.OutputPaths, logFile)
+	}
 
 	level := zapcore.WarnLevel
 	switch logLevel {
@@ -36,9 +40,21 @@ func initLogger(logLevel, logFile string) *zap.Logger {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "convert":
+			os.Exit(runConvert(os.Args[2:], os.Stdout, os.Stderr))
+		case "check":
+			os.Exit(runCheck(os.Args[2:], os.Stdout, os.Stderr))
+		case "filter":
+			os.Exit(runFilter(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		}
+	}
+
 	logLevel, logFile := parseFlags()
 	logger := initLogger(logLevel, logFile)
-	defer logger.Sync()
 
-	StartServer(logger)
+	code := StartServer(logger)
+	logger.Sync()
+	os.Exit(code)
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"go.lsp.dev/protocol"
+)
+
+// positionEncoding is the unit LSP character offsets are counted in. The
+// protocol package predates LSP 3.17, so the negotiation is decoded by hand.
+type positionEncoding string
+
+const (
+	positionEncodingUTF8  positionEncoding = "utf-8"
+	positionEncodingUTF16 positionEncoding = "utf-16"
+	positionEncodingUTF32 positionEncoding = "utf-32"
+)
+
+// negotiatePositionEncoding picks the encoding to use from the ones the
+// client supports. utf-8 is preferred as it matches our byte offsets, and
+// utf-16 is the default every client has to support.
+func negotiatePositionEncoding(supported []string) positionEncoding {
+	for _, preferred := range []positionEncoding{positionEncodingUTF8, positionEncodingUTF32} {
+		for _, encoding := range supported {
+			if positionEncoding(encoding) == preferred {
+				return preferred
+			}
+		}
+	}
+	return positionEncodingUTF16
+}
+
+// byteOffset converts a character offset in line to a byte offset, clamped
+// to the length of the line
+func (e positionEncoding) byteOffset(line string, character uint32) int {
+	if e == positionEncodingUTF8 {
+		return min(int(character), len(line))
+	}
+
+	units := 0
+	for i, r := range line {
+		if units >= int(character) {
+			return i
+		}
+		units += e.runeUnits(r)
+	}
+	return len(line)
+}
+
+// character converts a byte offset in line to a character offset
+func (e positionEncoding) character(line string, offset int) uint32 {
+	offset = min(offset, len(line))
+	if e == positionEncodingUTF8 {
+		return uint32(offset)
+	}
+
+	units := 0
+	for _, r := range line[:offset] {
+		units += e.runeUnits(r)
+	}
+	return uint32(units)
+}
+
+// lineRange returns the range between two byte offsets of a line
+func (e positionEncoding) lineRange(lineNum uint32, line string, start, end int) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: lineNum, Character: e.character(line, start)},
+		End:   protocol.Position{Line: lineNum, Character: e.character(line, end)},
+	}
+}
+
+func (e positionEncoding) runeUnits(r rune) int {
+	if e == positionEncodingUTF16 && r > 0xFFFF {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestNegotiatePositionEncoding(t *testing.T) {
+	tests := []struct {
+		supported []string
+		expected  positionEncoding
+	}{
+		{nil, positionEncodingUTF16},
+		{[]string{"utf-16"}, positionEncodingUTF16},
+		{[]string{"utf-16", "utf-8"}, positionEncodingUTF8},
+		{[]string{"utf-32", "utf-16"}, positionEncodingUTF32},
+		{[]string{"utf-32", "utf-8"}, positionEncodingUTF8},
+		{[]string{"latin-1"}, positionEncodingUTF16},
+	}
+
+	for _, tt := range tests {
+		if got := negotiatePositionEncoding(tt.supported); got != tt.expected {
+			t.Errorf("negotiatePositionEncoding(%v) = %s, want %s", tt.supported, got, tt.expected)
+		}
+	}
+}
+
+func TestPositionEncodingOffsets(t *testing.T) {
+	// "/* 宽度 😀 */ 348px": the CJK characters take 3 bytes, the emoji 4 bytes
+	// and two UTF-16 code units
+	line := "/* 宽度 😀 */ 348px"
+	pxStart := len("/* 宽度 😀 */ ")
+
+	tests := []struct {
+		encoding  positionEncoding
+		character uint32
+	}{
+		{positionEncodingUTF8, uint32(pxStart)},
+		{positionEncodingUTF16, 12},
+		{positionEncodingUTF32, 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.encoding), func(t *testing.T) {
+			if got := tt.encoding.character(line, pxStart); got != tt.character {
+				t.Errorf("character(%d) = %d, want %d", pxStart, got, tt.character)
+			}
+			if got := tt.encoding.byteOffset(line, tt.character); got != pxStart {
+				t.Errorf("byteOffset(%d) = %d, want %d", tt.character, got, pxStart)
+			}
+			if got := tt.encoding.byteOffset(line, 1000); got != len(line) {
+				t.Errorf("byteOffset past the end = %d, want %d", got, len(line))
+			}
+			if got := tt.encoding.character(line, len(line)+10); got != tt.encoding.character(line, len(line)) {
+				t.Errorf("character past the end = %d", got)
+			}
+		})
+	}
+}
+
+func TestCompletionAfterMultibyteText(t *testing.T) {
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3}
+	handler, uri := newTestHandler(t, ".a { /* 宽度😀 */ width: 348px; }", config)
+
+	// 348px ends at UTF-16 offset 28, byte offset 34
+	items, err := handler.Completion(context.Background(), &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 0, Character: 28},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items.Items) != 1 {
+		t.Fatalf("Expected 1 completion item, got %d", len(items.Items))
+	}
+
+	rng := items.Items[0].TextEdit.Range
+	if rng.Start.Character != 23 || rng.End.Character != 28 {
+		t.Errorf("Expected range 23-28, got %d-%d", rng.Start.Character, rng.End.Character)
+	}
+}
+
+func TestInitializePositionEncoding(t *testing.T) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	next := func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		t.Errorf("Unexpected call to the next handler for %s", req.Method())
+		return nil
+	}
+	h := extendedHandler(handler, next)
+
+	params := json.RawMessage(`{"capabilities":{"general":{"positionEncodings":["utf-32","utf-16"]}}}`)
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodInitialize, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []byte
+	err = h(context.Background(), func(ctx context.Context, r interface{}, err error) error {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		result, err = json.Marshal(r)
+		return err
+	}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Capabilities struct {
+			PositionEncoding string `json:"positionEncoding"`
+			HoverProvider    bool   `json:"hoverProvider"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(result, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Capabilities.PositionEncoding != "utf-32" || !decoded.Capabilities.HoverProvider {
+		t.Errorf("Unexpected capabilities: %s", result)
+	}
+	if handler.getPositionEncoding() != positionEncodingUTF32 {
+		t.Errorf("Expected the handler to use utf-32, got %s", handler.getPositionEncoding())
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+// errContentModified is protocol.ErrContentModified with a fitting message
+var errContentModified = jsonrpc2.NewError(protocol.CodeContentModified, "document modified while handling the request")
+
+// safeHandler keeps a failing request from taking the server down. Panics are
+// logged and answered with an internal error, errors from cancelled contexts
+// become RequestCancelled, and errors of notifications, which have no
+// response, are logged.
+func safeHandler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) (err error) {
+		var replied atomic.Bool
+		guardedReply := func(ctx context.Context, result interface{}, err error) error {
+			replied.Store(true)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				err = protocol.ErrRequestCancelled
+			}
+			if _, isCall := req.(*jsonrpc2.Call); !isCall && err != nil {
+				log.Sugar().Warnf("Failed to handle %s: %v", req.Method(), err)
+			}
+			return reply(ctx, result, err)
+		}
+
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			log.Error("Recovered from panic while handling a request",
+				zap.String("method", req.Method()), zap.Any("panic", r), zap.Stack("stack"))
+			if !replied.Load() {
+				err = reply(ctx, nil, jsonrpc2.NewError(jsonrpc2.InternalError,
+					fmt.Sprintf("internal error handling %s: %v", req.Method(), r)))
+			}
+		}()
+
+		return next(ctx, guardedReply, req)
+	}
+}
+
+// errInvalidParams returns an InvalidParams error with a formatted message
+func errInvalidParams(format string, args ...interface{}) error {
+	return jsonrpc2.NewError(jsonrpc2.InvalidParams, fmt.Sprintf(format, args...))
+}
+
+// documentLine returns the open document at uri and the line at pos, or an
+// InvalidParams error when the document isn't open or pos is past its end
+func (h *Handler) documentLine(uri protocol.DocumentURI, pos protocol.Position) (*document, string, error) {
+	doc, err := h.openDocument(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	lines := doc.Lines()
+	if int(pos.Line) >= len(lines) {
+		return nil, "", errInvalidParams("line %d outside %s of %d lines", pos.Line, uri, len(lines))
+	}
+	return doc, lines[pos.Line], nil
+}
+
+// openDocument returns the open document at uri, or an InvalidParams error
+func (h *Handler) openDocument(uri protocol.DocumentURI) (*document, error) {
+	doc, ok := h.documents.get(uri)
+	if !ok {
+		return nil, errInvalidParams("document not open: %s", uri)
+	}
+	return doc, nil
+}
+
+// checkModified returns ContentModified when the document a result was
+// computed from has since been changed or closed
+func (h *Handler) checkModified(uri protocol.DocumentURI, doc *document) error {
+	if current, ok := h.documents.get(uri); !ok || current != doc {
+		return errContentModified
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func errorCode(err error) jsonrpc2.Code {
+	var rpcErr *jsonrpc2.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code
+	}
+	return 0
+}
+
+func TestSafeHandler(t *testing.T) {
+	log = createTestLogger(t)
+
+	tests := []struct {
+		name       string
+		handler    jsonrpc2.Handler
+		expectCode jsonrpc2.Code
+	}{
+		{
+			name: "Panic",
+			handler: func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+				var lines []string
+				_ = lines[3]
+				return reply(ctx, nil, nil)
+			},
+			expectCode: jsonrpc2.InternalError,
+		},
+		{
+			name: "Cancelled context",
+			handler: func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+				return reply(ctx, nil, fmt.Errorf("walk: %w", context.Canceled))
+			},
+			expectCode: protocol.CodeRequestCancelled,
+		},
+		{
+			name: "Invalid params",
+			handler: func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+				return reply(ctx, nil, errInvalidParams("bad"))
+			},
+			expectCode: jsonrpc2.InvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodTextDocumentHover, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var replies []error
+			err = safeHandler(tt.handler)(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+				replies = append(replies, err)
+				return nil
+			}, call)
+			if err != nil {
+				t.Fatalf("Handler failed the connection: %v", err)
+			}
+			if len(replies) != 1 {
+				t.Fatalf("Expected 1 reply, got %d", len(replies))
+			}
+			if code := errorCode(replies[0]); code != tt.expectCode {
+				t.Errorf("Expected code %d, got %d (%v)", tt.expectCode, code, replies[0])
+			}
+		})
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	config := Config{ViewportWidth: 1440, UnitPrecision: 3, VwHover: true}
+	handler, uri := newTestHandler(t, ".a { width: 348px; }", config)
+	ctx := context.Background()
+	unknown := protocol.DocumentURI("file:///project/unknown.css")
+
+	position := func(uri protocol.DocumentURI, line, character uint32) protocol.TextDocumentPositionParams {
+		return protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: line, Character: character},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		request func() error
+	}{
+		{"Completion on unknown document", func() error {
+			_, err := handler.Completion(ctx, &protocol.CompletionParams{TextDocumentPositionParams: position(unknown, 0, 0)})
+			return err
+		}},
+		{"Completion past the last line", func() error {
+			_, err := handler.Completion(ctx, &protocol.CompletionParams{TextDocumentPositionParams: position(uri, 5, 0)})
+			return err
+		}},
+		{"Hover on unknown document", func() error {
+			_, err := handler.Hover(ctx, &protocol.HoverParams{TextDocumentPositionParams: position(unknown, 0, 0)})
+			return err
+		}},
+		{"Hover past the last line", func() error {
+			_, err := handler.Hover(ctx, &protocol.HoverParams{TextDocumentPositionParams: position(uri, 1, 0)})
+			return err
+		}},
+		{"Code action on unknown document", func() error {
+			_, err := handler.CodeAction(ctx, &protocol.CodeActionParams{TextDocument: protocol.TextDocumentIdentifier{URI: unknown}})
+			return err
+		}},
+		{"Code action with inverted range", func() error {
+			_, err := handler.CodeAction(ctx, &protocol.CodeActionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Range:        *rangeOf(1, 0, 0, 0),
+			})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := errorCode(tt.request()); code != jsonrpc2.InvalidParams {
+				t.Errorf("Expected InvalidParams, got code %d", code)
+			}
+		})
+	}
+
+	// a character past the end of the line is clamped rather than panicking
+	list, err := handler.Completion(ctx, &protocol.CompletionParams{TextDocumentPositionParams: position(uri, 0, 500)})
+	if err != nil || len(list.Items) != 0 {
+		t.Errorf("Expected no completions past the end of the line, got %v, %v", list, err)
+	}
+}
+
+func TestUnimplementedMethods(t *testing.T) {
+	conn := &recordingConn{}
+	handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+	handler.state = stateRunning
+	h := connHandler(handler)
+
+	tests := []struct {
+		name       string
+		request    func() (jsonrpc2.Request, error)
+		expectCode jsonrpc2.Code
+	}{
+		{
+			name: "Unimplemented request",
+			request: func() (jsonrpc2.Request, error) {
+				return jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodTextDocumentDefinition, &protocol.DefinitionParams{})
+			},
+			expectCode: jsonrpc2.MethodNotFound,
+		},
+		{
+			name: "Unknown request",
+			request: func() (jsonrpc2.Request, error) {
+				return jsonrpc2.NewCall(jsonrpc2.NewNumberID(2), "custom/unknown", nil)
+			},
+			expectCode: jsonrpc2.MethodNotFound,
+		},
+		{
+			name: "Unimplemented notification",
+			request: func() (jsonrpc2.Request, error) {
+				return jsonrpc2.NewNotification(protocol.MethodTextDocumentDidSave, &protocol.DidSaveTextDocumentParams{})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := tt.request()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			replies := make(chan error, 1)
+			err = h(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+				replies <- err
+				return nil
+			}, req)
+			if err != nil {
+				t.Fatalf("Handler failed the connection: %v", err)
+			}
+			if code := errorCode(<-replies); code != tt.expectCode {
+				t.Errorf("Expected code %d, got %d", tt.expectCode, code)
+			}
+		})
+	}
+
+	// nothing is passed on to the client
+	if len(conn.messages) != 0 {
+		t.Errorf("Expected no messages to the client, got %+v", conn.messages)
+	}
+}
@@ -14,9 +14,21 @@ type SchemaJson struct {
 	// Automatically remove prefix 0
 	AutoRemovePrefixZero bool `json:"autoRemovePrefixZero,omitempty" yaml:"autoRemovePrefixZero,omitempty" mapstructure:"autoRemovePrefixZero,omitempty"`
 
+	// Properties whose px values are not reported by diagnostics, e.g. `border-width`
+	AllowedPxProperties []string `json:"allowedPxProperties,omitempty" yaml:"allowedPxProperties,omitempty" mapstructure:"allowedPxProperties,omitempty"`
+
+	// Design widths of responsive @media blocks, the first breakpoint whose `media` is part of the enclosing @media condition applies
+	Breakpoints []SchemaJsonBreakpointsElem `json:"breakpoints,omitempty" yaml:"breakpoints,omitempty" mapstructure:"breakpoints,omitempty"`
+
+	// Also convert px values in @media, @container and @supports conditions, default: false
+	ConvertConditions bool `json:"convertConditions,omitempty" yaml:"convertConditions,omitempty" mapstructure:"convertConditions,omitempty"`
+
 	// Whether to display mark in after line, `disabled`: Disabled, `show` Show
 	CurrentLine SchemaJsonCurrentLine `json:"currentLine,omitempty" yaml:"currentLine,omitempty" mapstructure:"currentLine,omitempty"`
 
+	// Severity of diagnostics reported for raw px values, default: `off`
+	Diagnostics SchemaJsonDiagnostics `json:"diagnostics,omitempty" yaml:"diagnostics,omitempty" mapstructure:"diagnostics,omitempty"`
+
 	// Px to rem decimal point maximum length
 	FixedDigits float64 `json:"fixedDigits,omitempty" yaml:"fixedDigits,omitempty" mapstructure:"fixedDigits,omitempty"`
 
@@ -32,6 +44,9 @@ type SchemaJson struct {
 	// 支持语言清单
 	Languages []string `json:"languages,omitempty" yaml:"languages,omitempty" mapstructure:"languages,omitempty"`
 
+	// Viewport width fluid clamp() sizes start growing at, default: 375
+	MinVwDesign float64 `json:"minVwDesign,omitempty" yaml:"minVwDesign,omitempty" mapstructure:"minVwDesign,omitempty"`
+
 	// Whether to enable rem hover
 	RemHover bool `json:"remHover,omitempty" yaml:"remHover,omitempty" mapstructure:"remHover,omitempty"`
 
@@ -59,6 +74,35 @@ type SchemaJson struct {
 	WxssScreenWidth float64 `json:"wxssScreenWidth,omitempty" yaml:"wxssScreenWidth,omitempty" mapstructure:"wxssScreenWidth,omitempty"`
 }
 
+type SchemaJsonBreakpointsElem struct {
+	// Media condition of the breakpoint, e.g. `(max-width: 768px)`
+	Media string `json:"media" yaml:"media" mapstructure:"media"`
+
+	// Design width inside @media blocks with the condition
+	VwDesign float64 `json:"vwDesign" yaml:"vwDesign" mapstructure:"vwDesign"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *SchemaJsonBreakpointsElem) UnmarshalJSON(value []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(value, &raw); err != nil {
+		return err
+	}
+	if _, ok := raw["media"]; raw != nil && !ok {
+		return fmt.Errorf("field media in SchemaJsonBreakpointsElem: required")
+	}
+	if _, ok := raw["vwDesign"]; raw != nil && !ok {
+		return fmt.Errorf("field vwDesign in SchemaJsonBreakpointsElem: required")
+	}
+	type Plain SchemaJsonBreakpointsElem
+	var plain Plain
+	if err := json.Unmarshal(value, &plain); err != nil {
+		return err
+	}
+	*j = SchemaJsonBreakpointsElem(plain)
+	return nil
+}
+
 type SchemaJsonCurrentLine string
 
 const SchemaJsonCurrentLineDisabled SchemaJsonCurrentLine = "disabled"
@@ -89,6 +133,42 @@ func (j *SchemaJsonCurrentLine) UnmarshalJSON(value []byte) error {
 	return nil
 }
 
+type SchemaJsonDiagnostics string
+
+const SchemaJsonDiagnosticsError SchemaJsonDiagnostics = "error"
+const SchemaJsonDiagnosticsHint SchemaJsonDiagnostics = "hint"
+const SchemaJsonDiagnosticsInformation SchemaJsonDiagnostics = "information"
+const SchemaJsonDiagnosticsOff SchemaJsonDiagnostics = "off"
+const SchemaJsonDiagnosticsWarning SchemaJsonDiagnostics = "warning"
+
+var enumValues_SchemaJsonDiagnostics = []interface{}{
+	"off",
+	"error",
+	"warning",
+	"information",
+	"hint",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *SchemaJsonDiagnostics) UnmarshalJSON(value []byte) error {
+	var v string
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	var ok bool
+	for _, expected := range enumValues_SchemaJsonDiagnostics {
+		if reflect.DeepEqual(v, expected) {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("invalid value (expected one of %#v): %#v", enumValues_SchemaJsonDiagnostics, v)
+	}
+	*j = SchemaJsonDiagnostics(v)
+	return nil
+}
+
 type SchemaJsonHover string
 
 const SchemaJsonHoverAlways SchemaJsonHover = "always"
@@ -135,12 +215,24 @@ func (j *SchemaJson) UnmarshalJSON(value []byte) error {
 	if v, ok := raw["addMark"]; !ok || v == nil {
 		plain.AddMark = false
 	}
+	if v, ok := raw["allowedPxProperties"]; !ok || v == nil {
+		plain.AllowedPxProperties = []string{}
+	}
 	if v, ok := raw["autoRemovePrefixZero"]; !ok || v == nil {
 		plain.AutoRemovePrefixZero = true
 	}
+	if v, ok := raw["breakpoints"]; !ok || v == nil {
+		plain.Breakpoints = []SchemaJsonBreakpointsElem{}
+	}
+	if v, ok := raw["convertConditions"]; !ok || v == nil {
+		plain.ConvertConditions = false
+	}
 	if v, ok := raw["currentLine"]; !ok || v == nil {
 		plain.CurrentLine = "show"
 	}
+	if v, ok := raw["diagnostics"]; !ok || v == nil {
+		plain.Diagnostics = "off"
+	}
 	if v, ok := raw["fixedDigits"]; !ok || v == nil {
 		plain.FixedDigits = 6.0
 	}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 	"go.uber.org/multierr"
@@ -10,7 +12,9 @@ import (
 	"os"
 )
 
-func StartServer(logger *zap.Logger) {
+// StartServer serves LSP on stdin and stdout until the client exits, and
+// returns the status the process should exit with
+func StartServer(logger *zap.Logger) int {
 	stream := &readWriteCloser{os.Stdin, os.Stdout}
 	conn := jsonrpc2.NewConn(jsonrpc2.NewStream(stream))
 
@@ -24,16 +28,110 @@ func StartServer(logger *zap.Logger) {
 
 	handler, ctx, err := NewHandler(
 		context.Background(),
-		protocol.ServerDispatcher(conn, logger),
+		conn,
 		logger,
 		globalConfig,
 	)
 	if err != nil {
 		logger.Sugar().Fatalf("init handler error: %v", err)
 	}
+	defer handler.Close()
 
-	conn.Go(ctx, protocol.ServerHandler(handler, jsonrpc2.MethodNotFoundHandler))
+	conn.Go(ctx, connHandler(handler))
 	<-conn.Done()
+	return handler.ExitCode()
+}
+
+// connHandler layers the jsonrpc2 handlers of the connection around handler
+func connHandler(handler *Handler) jsonrpc2.Handler {
+	h := protocol.ServerHandler(handler, jsonrpc2.MethodNotFoundHandler)
+	h = extendedHandler(handler, h)
+	h = lifecycleHandler(handler, h)
+	h = safeHandler(h)
+	h = concurrentHandler(h)
+	return cancelRequestHandler(h)
+}
+
+// concurrentHandler handles each call in its own goroutine, so a slow request
+// doesn't hold up the connection. Notifications are handled on the reading
+// goroutine, so document changes are applied in the order they were sent and
+// before any request that follows them.
+func concurrentHandler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if _, ok := req.(*jsonrpc2.Call); !ok {
+			return next(ctx, reply, req)
+		}
+
+		go func() {
+			if err := next(ctx, reply, req); err != nil {
+				log.Sugar().Errorf("Failed to handle %s: %v", req.Method(), err)
+			}
+		}()
+		return nil
+	}
+}
+
+// initializeCapabilities are the LSP 3.17 client capabilities the protocol
+// package doesn't decode
+type initializeCapabilities struct {
+	Capabilities struct {
+		General struct {
+			PositionEncodings []string `json:"positionEncodings"`
+		} `json:"general"`
+		Workspace struct {
+			InlayHint struct {
+				RefreshSupport bool `json:"refreshSupport"`
+			} `json:"inlayHint"`
+			DidChangeWatchedFiles struct {
+				RelativePatternSupport bool `json:"relativePatternSupport"`
+			} `json:"didChangeWatchedFiles"`
+		} `json:"workspace"`
+	} `json:"capabilities"`
+}
+
+// extendedHandler decodes the messages the protocol package can't fully
+// represent itself: the LSP 3.17 capabilities of initialize, didChange as the
+// protocol package turns a content change without a range into an edit at
+// 0:0, shutdown and exit which it rejects when sent with null params, and
+// textDocument/inlayHint which it doesn't know
+func extendedHandler(handler *Handler, next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		switch req.Method() {
+		case protocol.MethodShutdown:
+			return reply(ctx, nil, handler.Shutdown(ctx))
+
+		case protocol.MethodExit:
+			return reply(ctx, nil, handler.Exit(ctx))
+
+		case protocol.MethodInitialize:
+			var params protocol.InitializeParams
+			var capabilities initializeCapabilities
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+			}
+			if err := json.Unmarshal(req.Params(), &capabilities); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+			}
+			result, err := handler.initialize(ctx, &params, &capabilities)
+			return reply(ctx, result, err)
+
+		case methodTextDocumentInlayHint:
+			var params inlayHintParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+			}
+			result, err := handler.inlayHints(ctx, &params)
+			return reply(ctx, result, err)
+
+		case protocol.MethodTextDocumentDidChange:
+			var params didChangeParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%s: %w", jsonrpc2.ErrParse, err))
+			}
+			return reply(ctx, nil, handler.didChange(ctx, &params))
+		}
+		return next(ctx, reply, req)
+	}
 }
 
 type readWriteCloser struct {
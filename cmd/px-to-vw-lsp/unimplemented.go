@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+var _ protocol.Server = (*Handler)(nil)
+
+// unimplementedServer is the protocol.Server the Handler falls back to for
+// the methods it doesn't implement. Requests are rejected with MethodNotFound
+// and notifications are dropped.
+type unimplementedServer struct{}
+
+// errMethodNotFound returns a MethodNotFound error for method
+func errMethodNotFound(method string) error {
+	return jsonrpc2.NewError(jsonrpc2.MethodNotFound, fmt.Sprintf("method not supported: %s", method))
+}
+
+func (unimplementedServer) LogTrace(context.Context, *protocol.LogTraceParams) error {
+	return nil
+}
+
+func (unimplementedServer) SetTrace(context.Context, *protocol.SetTraceParams) error {
+	return nil
+}
+
+func (unimplementedServer) CodeLensResolve(context.Context, *protocol.CodeLens) (*protocol.CodeLens, error) {
+	return nil, errMethodNotFound(protocol.MethodCodeLensResolve)
+}
+
+func (unimplementedServer) ColorPresentation(context.Context, *protocol.ColorPresentationParams) ([]protocol.ColorPresentation, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentColorPresentation)
+}
+
+func (unimplementedServer) CompletionResolve(context.Context, *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	return nil, errMethodNotFound(protocol.MethodCompletionItemResolve)
+}
+
+func (unimplementedServer) Declaration(context.Context, *protocol.DeclarationParams) ([]protocol.Location, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentDeclaration)
+}
+
+func (unimplementedServer) Definition(context.Context, *protocol.DefinitionParams) ([]protocol.Location, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentDefinition)
+}
+
+func (unimplementedServer) DidChangeConfiguration(context.Context, *protocol.DidChangeConfigurationParams) error {
+	return nil
+}
+
+func (unimplementedServer) DidSave(context.Context, *protocol.DidSaveTextDocumentParams) error {
+	return nil
+}
+
+func (unimplementedServer) DocumentColor(context.Context, *protocol.DocumentColorParams) ([]protocol.ColorInformation, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentDocumentColor)
+}
+
+func (unimplementedServer) DocumentHighlight(context.Context, *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentDocumentHighlight)
+}
+
+func (unimplementedServer) DocumentLink(context.Context, *protocol.DocumentLinkParams) ([]protocol.DocumentLink, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentDocumentLink)
+}
+
+func (unimplementedServer) DocumentLinkResolve(context.Context, *protocol.DocumentLink) (*protocol.DocumentLink, error) {
+	return nil, errMethodNotFound(protocol.MethodDocumentLinkResolve)
+}
+
+func (unimplementedServer) DocumentSymbol(context.Context, *protocol.DocumentSymbolParams) ([]interface{}, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentDocumentSymbol)
+}
+
+func (unimplementedServer) FoldingRanges(context.Context, *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentFoldingRange)
+}
+
+func (unimplementedServer) Formatting(context.Context, *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentFormatting)
+}
+
+func (unimplementedServer) Implementation(context.Context, *protocol.ImplementationParams) ([]protocol.Location, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentImplementation)
+}
+
+func (unimplementedServer) OnTypeFormatting(context.Context, *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentOnTypeFormatting)
+}
+
+func (unimplementedServer) PrepareRename(context.Context, *protocol.PrepareRenameParams) (*protocol.Range, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentPrepareRename)
+}
+
+func (unimplementedServer) RangeFormatting(context.Context, *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentRangeFormatting)
+}
+
+func (unimplementedServer) References(context.Context, *protocol.ReferenceParams) ([]protocol.Location, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentReferences)
+}
+
+func (unimplementedServer) Rename(context.Context, *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentRename)
+}
+
+func (unimplementedServer) SignatureHelp(context.Context, *protocol.SignatureHelpParams) (*protocol.SignatureHelp, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentSignatureHelp)
+}
+
+func (unimplementedServer) Symbols(context.Context, *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	return nil, errMethodNotFound(protocol.MethodWorkspaceSymbol)
+}
+
+func (unimplementedServer) TypeDefinition(context.Context, *protocol.TypeDefinitionParams) ([]protocol.Location, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentTypeDefinition)
+}
+
+func (unimplementedServer) WillSave(context.Context, *protocol.WillSaveTextDocumentParams) error {
+	return nil
+}
+
+func (unimplementedServer) WillSaveWaitUntil(context.Context, *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentWillSaveWaitUntil)
+}
+
+func (unimplementedServer) ShowDocument(context.Context, *protocol.ShowDocumentParams) (*protocol.ShowDocumentResult, error) {
+	return nil, errMethodNotFound(protocol.MethodShowDocument)
+}
+
+func (unimplementedServer) WillCreateFiles(context.Context, *protocol.CreateFilesParams) (*protocol.WorkspaceEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodWillCreateFiles)
+}
+
+func (unimplementedServer) DidCreateFiles(context.Context, *protocol.CreateFilesParams) error {
+	return nil
+}
+
+func (unimplementedServer) WillRenameFiles(context.Context, *protocol.RenameFilesParams) (*protocol.WorkspaceEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodWillRenameFiles)
+}
+
+func (unimplementedServer) DidRenameFiles(context.Context, *protocol.RenameFilesParams) error {
+	return nil
+}
+
+func (unimplementedServer) WillDeleteFiles(context.Context, *protocol.DeleteFilesParams) (*protocol.WorkspaceEdit, error) {
+	return nil, errMethodNotFound(protocol.MethodWillDeleteFiles)
+}
+
+func (unimplementedServer) DidDeleteFiles(context.Context, *protocol.DeleteFilesParams) error {
+	return nil
+}
+
+func (unimplementedServer) CodeLensRefresh(context.Context) error {
+	return errMethodNotFound(protocol.MethodCodeLensRefresh)
+}
+
+func (unimplementedServer) PrepareCallHierarchy(context.Context, *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
+	return nil, errMethodNotFound(protocol.MethodTextDocumentPrepareCallHierarchy)
+}
+
+func (unimplementedServer) IncomingCalls(context.Context, *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	return nil, errMethodNotFound(protocol.MethodCallHierarchyIncomingCalls)
+}
+
+func (unimplementedServer) OutgoingCalls(context.Context, *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	return nil, errMethodNotFound(protocol.MethodCallHierarchyOutgoingCalls)
+}
+
+func (unimplementedServer) SemanticTokensFull(context.Context, *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	return nil, errMethodNotFound(protocol.MethodSemanticTokensFull)
+}
+
+func (unimplementedServer) SemanticTokensFullDelta(context.Context, *protocol.SemanticTokensDeltaParams) (interface{}, error) {
+	return nil, errMethodNotFound(protocol.MethodSemanticTokensFullDelta)
+}
+
+func (unimplementedServer) SemanticTokensRange(context.Context, *protocol.SemanticTokensRangeParams) (*protocol.SemanticTokens, error) {
+	return nil, errMethodNotFound(protocol.MethodSemanticTokensRange)
+}
+
+func (unimplementedServer) SemanticTokensRefresh(context.Context) error {
+	return errMethodNotFound(protocol.MethodSemanticTokensRefresh)
+}
+
+func (unimplementedServer) LinkedEditingRange(context.Context, *protocol.LinkedEditingRangeParams) (*protocol.LinkedEditingRanges, error) {
+	return nil, errMethodNotFound(protocol.MethodLinkedEditingRange)
+}
+
+func (unimplementedServer) Moniker(context.Context, *protocol.MonikerParams) ([]protocol.Moniker, error) {
+	return nil, errMethodNotFound(protocol.MethodMoniker)
+}
+
+func (unimplementedServer) Request(_ context.Context, method string, _ interface{}) (interface{}, error) {
+	return nil, errMethodNotFound(method)
+}
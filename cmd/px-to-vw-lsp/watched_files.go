@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+const watchedFilesRegistrationID = "px-to-vw-lsp/watchedFiles"
+
+func (h *Handler) Initialized(ctx context.Context, params *protocol.InitializedParams) error {
+	h.configsMu.RLock()
+	clientWatchesFiles := h.clientWatchesFiles
+	h.configsMu.RUnlock()
+
+	if clientWatchesFiles {
+		// the client only answers client/registerCapability once this
+		// notification has been handled, so register in the background
+		go h.registerWatchedFiles(h.ctx)
+	}
+	return nil
+}
+
+// fileSystemWatcher is protocol.FileSystemWatcher with the LSP 3.17 glob
+// pattern, either a string or a relativePattern
+type fileSystemWatcher struct {
+	GlobPattern interface{} `json:"globPattern"`
+}
+
+// relativePattern is a glob matched against paths under BaseURI
+type relativePattern struct {
+	BaseURI protocol.URI `json:"baseUri"`
+	Pattern string       `json:"pattern"`
+}
+
+type didChangeWatchedFilesRegistrationOptions struct {
+	Watchers []fileSystemWatcher `json:"watchers"`
+}
+
+// registerWatchedFiles asks the client to watch the config files for us,
+// falling back to server-side watching when it refuses
+func (h *Handler) registerWatchedFiles(ctx context.Context) {
+	if h.client == nil {
+		h.fallBackToServerWatching()
+		return
+	}
+
+	h.configsMu.RLock()
+	relative := h.clientWatchesRelativePatterns
+	h.configsMu.RUnlock()
+
+	watchers := []fileSystemWatcher{{GlobPattern: "**/.cssrem"}}
+	// many clients only report changes under the workspace folders, so the
+	// global config is left to the server unless it can be watched relative
+	// to its own directory
+	watchesGlobal := relative && h.globalConfig != nil && h.globalConfig.Path() != ""
+	if watchesGlobal {
+		path := h.globalConfig.Path()
+		watchers = append(watchers, fileSystemWatcher{GlobPattern: relativePattern{
+			BaseURI: uri.File(filepath.Dir(path)),
+			Pattern: filepath.Base(path),
+		}})
+	}
+
+	err := h.client.RegisterCapability(ctx, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:              watchedFilesRegistrationID,
+			Method:          protocol.MethodWorkspaceDidChangeWatchedFiles,
+			RegisterOptions: didChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}},
+	})
+	if err != nil {
+		log.Sugar().Warnf("Failed to register watched files, watching config files in the server: %v", err)
+		h.fallBackToServerWatching()
+		return
+	}
+
+	log.Sugar().Infof("Registered %d watched file patterns with the client", len(watchers))
+	if watchesGlobal {
+		h.globalConfig.Close()
+	}
+}
+
+// fallBackToServerWatching starts server-side watchers for every workspace folder
+func (h *Handler) fallBackToServerWatching() {
+	h.configsMu.Lock()
+	h.clientWatchesFiles = false
+	folders := make([]string, 0, len(h.configs))
+	for folderPath := range h.configs {
+		folders = append(folders, folderPath)
+	}
+	h.configsMu.Unlock()
+
+	for _, folderPath := range folders {
+		h.watchConfigFolder(folderPath)
+	}
+}
+
+func (h *Handler) DidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	for _, change := range params.Changes {
+		path := strings.TrimPrefix(string(change.URI), "file://")
+		log.Sugar().Debugf("Watched file changed: %s (type %v)", path, change.Type)
+
+		if h.globalConfig != nil && path == h.globalConfig.Path() {
+			// reloading the global config re-merges every workspace folder
+			h.globalConfig.Reload(log)
+			continue
+		}
+
+		if filepath.Base(path) == ".cssrem" {
+			h.reloadConfigFolder(filepath.Dir(path))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+func TestDidChangeWatchedFiles(t *testing.T) {
+	handler, _, _ := NewHandler(context.Background(), nil, createTestLogger(t), nil)
+	defer handler.Close()
+	handler.clientWatchesFiles = true
+
+	folder := t.TempDir()
+	handler.addConfigFolder(folder)
+	if len(handler.configWatchers) != 0 {
+		t.Errorf("Expected no server-side watchers when the client watches files, got %d", len(handler.configWatchers))
+	}
+
+	cssremPath := filepath.Join(folder, ".cssrem")
+	if err := os.WriteFile(cssremPath, []byte(`{"vwDesign": 1920}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	err := handler.DidChangeWatchedFiles(context.Background(), &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{Type: protocol.FileChangeTypeCreated, URI: uri.File(cssremPath)}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	uri := protocol.DocumentURI("file://" + filepath.Join(folder, "style.css"))
+	if got := handler.getConfigForDocument(uri).ViewportWidth; got != 1920 {
+		t.Errorf("ViewportWidth: got %f, want 1920", got)
+	}
+}
+
+func TestRegisterWatchedFiles(t *testing.T) {
+	t.Run("Client accepts registration", func(t *testing.T) {
+		conn := &recordingConn{}
+		handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+		defer handler.Close()
+		handler.clientWatchesFiles = true
+		handler.addConfigFolder(t.TempDir())
+
+		handler.registerWatchedFiles(context.Background())
+
+		registrations := conn.sent(protocol.MethodClientRegisterCapability)
+		if len(registrations) != 1 {
+			t.Fatalf("Expected 1 registration, got %d", len(registrations))
+		}
+		params := registrations[0].params.(*protocol.RegistrationParams)
+		if params.Registrations[0].Method != protocol.MethodWorkspaceDidChangeWatchedFiles {
+			t.Errorf("Registered method: got %q", params.Registrations[0].Method)
+		}
+		if len(handler.configWatchers) != 0 {
+			t.Errorf("Expected no server-side watchers, got %d", len(handler.configWatchers))
+		}
+	})
+
+	for _, relative := range []bool{false, true} {
+		name := "Global config without relative patterns"
+		if relative {
+			name = "Global config relative to its directory"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			globalConfig, err := NewGlobalConfig(context.Background(), createTestLogger(t))
+			if err != nil {
+				t.Fatalf("Failed to create global config: %v", err)
+			}
+			defer globalConfig.Close()
+			conn := &recordingConn{}
+			handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), globalConfig)
+			defer handler.Close()
+			handler.clientWatchesFiles = true
+			handler.clientWatchesRelativePatterns = relative
+
+			handler.registerWatchedFiles(context.Background())
+
+			registrations := conn.sent(protocol.MethodClientRegisterCapability)
+			if len(registrations) != 1 {
+				t.Fatalf("Expected 1 registration, got %d", len(registrations))
+			}
+			options := registrations[0].params.(*protocol.RegistrationParams).Registrations[0].RegisterOptions
+			watchers := options.(didChangeWatchedFilesRegistrationOptions).Watchers
+			globalConfig.mu.RLock()
+			serverWatches := globalConfig.watcher != nil
+			globalConfig.mu.RUnlock()
+
+			if relative {
+				expected := relativePattern{
+					BaseURI: uri.File(filepath.Dir(globalConfig.Path())),
+					Pattern: "config.json",
+				}
+				if len(watchers) != 2 || watchers[1].GlobPattern != expected {
+					t.Errorf("Expected the global config watched as %v, got %v", expected, watchers)
+				}
+				if serverWatches {
+					t.Error("Expected the server-side global watcher to stop")
+				}
+			} else {
+				if len(watchers) != 1 {
+					t.Errorf("Expected only the .cssrem pattern, got %v", watchers)
+				}
+				if !serverWatches {
+					t.Error("Expected the server-side global watcher to keep running")
+				}
+			}
+		})
+	}
+
+	t.Run("Client rejects registration", func(t *testing.T) {
+		conn := &recordingConn{errs: map[string]error{
+			protocol.MethodClientRegisterCapability: errors.New("not supported"),
+		}}
+		handler, _, _ := NewHandler(context.Background(), conn, createTestLogger(t), nil)
+		defer handler.Close()
+		handler.clientWatchesFiles = true
+		handler.addConfigFolder(t.TempDir())
+
+		handler.registerWatchedFiles(context.Background())
+
+		if handler.clientWatchesFiles {
+			t.Errorf("Expected fallback to server-side watching")
+		}
+		if len(handler.configWatchers) != 1 {
+			t.Errorf("Expected 1 server-side watcher, got %d", len(handler.configWatchers))
+		}
+	})
+}
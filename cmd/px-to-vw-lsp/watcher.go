@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pollInterval is how often watched files are checked for changes when
+// event based watching isn't available
+var pollInterval = 2 * time.Second
+
+// debounceInterval is how long a burst of file events has to settle before
+// onChange is called, editors often write a file in several steps
+var debounceInterval = 100 * time.Millisecond
+
+// fileWatcher monitors a file and calls onChange when it is created, modified,
+// deleted or renamed. It watches the parent directory for events where the
+// platform supports it, so atomic rename-replace saves are seen, and falls
+// back to stat polling otherwise.
+type fileWatcher struct {
+	path     string
+	onChange func()
+	logger   *zap.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// newFileWatcher starts monitoring path in a goroutine until ctx is done or Close is called
+func newFileWatcher(ctx context.Context, path string, logger *zap.Logger, onChange func()) *fileWatcher {
+	// Create a dedicated context for file watching with cancellation
+	watcherCtx, cancel := context.WithCancel(ctx)
+
+	w := &fileWatcher{
+		path:     path,
+		onChange: onChange,
+		logger:   logger,
+		ctx:      watcherCtx,
+		cancel:   cancel,
+	}
+
+	events, err := watchDir(watcherCtx, filepath.Dir(path))
+	if err != nil {
+		logger.Sugar().Debugf("Polling %s for changes: %v", path, err)
+		lastModTime, exists := w.stat()
+		go w.monitorFile(pollInterval, lastModTime, exists)
+		return w
+	}
+
+	go w.handleEvents(events, debounceInterval, pollInterval)
+	return w
+}
+
+// handleEvents debounces directory events concerning the watched file. If the
+// directory stops being watchable, e.g. because it was removed, it falls back to polling.
+func (w *fileWatcher) handleEvents(events <-chan string, debounce, interval time.Duration) {
+	name := filepath.Base(w.path)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				if w.ctx.Err() != nil {
+					return
+				}
+				w.logger.Sugar().Debugf("Lost directory watch for %s, polling instead", w.path)
+				lastModTime, exists := w.stat()
+				w.onChange()
+				w.monitorFile(interval, lastModTime, exists)
+				return
+			}
+			if event == name {
+				timer.Reset(debounce)
+			}
+		case <-timer.C:
+			w.onChange()
+		}
+	}
+}
+
+// monitorFile watches for file changes using stat polling
+func (w *fileWatcher) monitorFile(interval time.Duration, lastModTime time.Time, exists bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, nowExists := w.stat()
+			if nowExists != exists || !modTime.Equal(lastModTime) {
+				lastModTime, exists = modTime, nowExists
+				w.onChange()
+			}
+		}
+	}
+}
+
+// stat returns the modification time of the file and whether it exists
+func (w *fileWatcher) stat() (time.Time, bool) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// Close stops the watcher goroutine
+func (w *fileWatcher) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+}
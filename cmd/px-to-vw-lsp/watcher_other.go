@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// watchDir is only implemented with inotify, other platforms poll
+func watchDir(ctx context.Context, dir string) (<-chan string, error) {
+	return nil, errors.New("directory events not supported on this platform")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	oldDebounce, oldInterval := debounceInterval, pollInterval
+	debounceInterval, pollInterval = 50*time.Millisecond, 20*time.Millisecond
+	defer func() { debounceInterval, pollInterval = oldDebounce, oldInterval }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	var changes atomic.Int32
+	watcher := newFileWatcher(context.Background(), path, createTestLogger(t), func() { changes.Add(1) })
+	defer watcher.Close()
+
+	waitForChanges := func(expected int32) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) && changes.Load() < expected {
+			time.Sleep(5 * time.Millisecond)
+		}
+		// give extra events a chance to show up
+		time.Sleep(3 * debounceInterval)
+		if got := changes.Load(); got != expected {
+			t.Fatalf("Changes: got %d, want %d", got, expected)
+		}
+	}
+
+	// create plus a burst of writes
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(path, []byte(`{"vwDesign": 1920}`), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	waitForChanges(1)
+
+	// atomic rename-replace, as done by many editors
+	tmp := filepath.Join(dir, "config.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"vwDesign": 2560}`), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	waitForChanges(2)
+
+	// unrelated files in the directory are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitForChanges(2)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	waitForChanges(3)
+}
@@ -0,0 +1,79 @@
+package convert
+
+import "strings"
+
+// Block is a `{...}` block of a stylesheet, a rule or an at-rule like
+// @media, with byte offsets into the text
+type Block struct {
+	// Start is the start of the selector or prelude, End the end of the
+	// closing "}", or of the text when the block is unterminated
+	Start int
+	End   int
+	// Prelude is the selector or at-rule prelude before the "{", e.g.
+	// "@media (min-width: 768px)"
+	Prelude string
+	// AtRule is the lowercased name of an at-rule block, e.g. "media",
+	// and empty for rules
+	AtRule string
+	// Depth is the number of blocks enclosing the block
+	Depth int
+}
+
+// Blocks returns the blocks of text, in the SCSS dialect, in the order they
+// open, so enclosing blocks come before the blocks nested in them
+func Blocks(text string) []Block {
+	return findBlocks(text, Tokenize(text, DialectSCSS))
+}
+
+// findBlocks returns the blocks of the tokens of text
+func findBlocks(text string, tokens []Token) []Block {
+	var blocks []Block
+	var open []int
+	start := 0
+	for i, token := range tokens {
+		switch token.Kind {
+		case TokenOpenBrace:
+			blocks = append(blocks, newBlock(text, tokens[start:i], token, len(open)))
+			open = append(open, len(blocks)-1)
+		case TokenCloseBrace:
+			if len(open) > 0 {
+				blocks[open[len(open)-1]].End = token.End
+				open = open[:len(open)-1]
+			}
+		case TokenSemicolon:
+		default:
+			continue
+		}
+		start = i + 1
+	}
+	return blocks
+}
+
+// newBlock returns the block opened by brace after the prelude tokens,
+// running to the end of the text until its "}" is found
+func newBlock(text string, prelude []Token, brace Token, depth int) Block {
+	block := Block{Start: brace.Start, End: len(text), Depth: depth}
+	var significant []Token
+	for _, token := range prelude {
+		if token.Kind != TokenWhitespace && token.Kind != TokenComment {
+			significant = append(significant, token)
+		}
+	}
+	if len(significant) == 0 {
+		return block
+	}
+
+	first, last := significant[0], significant[len(significant)-1]
+	block.Start = first.Start
+	block.Prelude = text[first.Start:last.End]
+	// a Less detached ruleset like `@detached: {` isn't an at-rule
+	if first.Kind == TokenAtKeyword && (len(significant) < 2 || significant[1].Kind != TokenColon) {
+		block.AtRule = strings.ToLower(strings.TrimPrefix(first.Text, "@"))
+	}
+	return block
+}
+
+// Contains reports whether the byte offset lies inside the block
+func (b Block) Contains(offset int) bool {
+	return offset >= b.Start && offset < b.End
+}
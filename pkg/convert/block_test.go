@@ -0,0 +1,80 @@
+package convert
+
+import (
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Block
+	}{
+		{
+			name:  "Rules",
+			input: ".a { width: 1px; }\n.b{}",
+			expected: []Block{
+				{Start: 0, End: 18, Prelude: ".a"},
+				{Start: 19, End: 23, Prelude: ".b"},
+			},
+		},
+		{
+			name:  "Media block",
+			input: "@media (min-width: 768px) {\n  .a { width: 1px; }\n}",
+			expected: []Block{
+				{Start: 0, End: 50, Prelude: "@media (min-width: 768px)", AtRule: "media"},
+				{Start: 30, End: 48, Prelude: ".a", Depth: 1},
+			},
+		},
+		{
+			name:  "Nested rule after a declaration",
+			input: ".a { margin: 0; &:hover { color: red } }",
+			expected: []Block{
+				{Start: 0, End: 40, Prelude: ".a"},
+				{Start: 16, End: 38, Prelude: "&:hover", Depth: 1},
+			},
+		},
+		{
+			name:  "Comment before the selector",
+			input: "/* card */ .card { }",
+			expected: []Block{
+				{Start: 11, End: 20, Prelude: ".card"},
+			},
+		},
+		{
+			name:  "Less detached ruleset",
+			input: "@detached: { width: 1px; }",
+			expected: []Block{
+				{Start: 0, End: 26, Prelude: "@detached:"},
+			},
+		},
+		{
+			name:  "Braces in strings and interpolation",
+			input: `.a { content: "}"; width: #{$w}; }`,
+			expected: []Block{
+				{Start: 0, End: 34, Prelude: ".a"},
+			},
+		},
+		{
+			name:  "Unterminated block",
+			input: ".a { width: 1px;",
+			expected: []Block{
+				{Start: 0, End: 16, Prelude: ".a"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := Blocks(tt.input)
+			if len(blocks) != len(tt.expected) {
+				t.Fatalf("Expected %+v, got %+v", tt.expected, blocks)
+			}
+			for i := range blocks {
+				if blocks[i] != tt.expected[i] {
+					t.Errorf("Block %d: expected %+v, got %+v", i, tt.expected[i], blocks[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// defaultRootFontSize is the browser default the rem offset of a clamp()
+// is relative to when RootFontSize is unset
+const defaultRootFontSize = 16
+
+// Range is a fluid size written as two px literals joined by "..", e.g.
+// "14px..20px", with byte offsets into the text
+type Range struct {
+	From  Match
+	To    Match
+	Start int
+	End   int
+}
+
+// FindRanges returns the "14px..20px" ranges in text
+func FindRanges(text string) []Range {
+	return NewScan(text, DialectSCSS).Ranges
+}
+
+// findRanges returns the ranges formed by the literals of text, and marks
+// those literals as range bounds
+func findRanges(text string, matches []Match) []Range {
+	var ranges []Range
+	for i := 1; i < len(matches); i++ {
+		if r, ok := newRange(text, matches[i-1], matches[i]); ok {
+			matches[i-1].Bound, matches[i].Bound = true, true
+			r.From.Bound, r.To.Bound = true, true
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// newRange returns the range of two consecutive literals joined by ".."
+func newRange(text string, from, to Match) (Range, bool) {
+	if from.Unit != "px" || to.Unit != "px" {
+		return Range{}, false
+	}
+	// the second dot of "14px..20px" is read as part of ".20px"
+	if strings.HasPrefix(to.Number, ".") && text[to.Start-1] == '.' {
+		value, err := strconv.ParseFloat(to.Number[1:], 64)
+		if err != nil {
+			return Range{}, false
+		}
+		to.Number, to.Value = to.Number[1:], value
+		to.Start++
+	}
+	if strings.Trim(text[from.End:to.Start], " \t") != ".." {
+		return Range{}, false
+	}
+	return Range{From: from, To: to, Start: from.Start, End: to.End}, true
+}
+
+// RangeAt returns the range touching the byte offset, if any
+func RangeAt(text string, offset int) (Range, bool) {
+	return NewScan(text, DialectSCSS).RangeAt(offset)
+}
+
+// RangeBefore returns the range ending exactly at the byte offset, if any.
+// Only the text before the offset is read, like when typing.
+func RangeBefore(text string, offset int) (Range, bool) {
+	offset = min(max(offset, 0), len(text))
+	return NewScan(text[:offset], DialectSCSS).RangeBefore(offset)
+}
+
+// RangeIn returns the range between the byte offsets start and end, like a
+// selection: a "14px..20px" range, or any two px literals as in "14px 20px"
+func RangeIn(text string, start, end int) (Range, bool) {
+	return NewScan(text, DialectSCSS).RangeIn(start, end)
+}
+
+// Clamp returns a clamp() expression growing linearly from the size of
+// from at MinViewportWidth to the size of to at the viewport width, e.g.
+// "clamp(14px, 0.563vw + 0.743rem, 20px)". Outside those widths the size
+// stays at the nearer bound.
+func (c *Converter) Clamp(from, to Match) (string, error) {
+	minWidth, maxWidth := c.config.MinViewportWidth, c.ViewportWidth(to)
+	if minWidth <= 0 || minWidth >= maxWidth {
+		return "", fmt.Errorf("minimum viewport width %v must be between 0 and the viewport width %v", minWidth, maxWidth)
+	}
+	rootFontSize := c.config.RootFontSize
+	if rootFontSize == 0 {
+		rootFontSize = defaultRootFontSize
+	}
+
+	slope := (to.Value - from.Value) / (maxWidth - minWidth)
+	offset := (from.Value - slope*minWidth) / rootFontSize
+	preferred := formatTrimmed(slope*100, c.config.UnitPrecision) + "vw"
+	if rem := formatTrimmed(math.Abs(offset), c.config.UnitPrecision); rem != "0" {
+		sign := "+"
+		if offset < 0 {
+			sign = "-"
+		}
+		preferred += " " + sign + " " + rem + "rem"
+	}
+
+	lower, upper := from, to
+	if lower.Value > upper.Value {
+		lower, upper = upper, lower
+	}
+	return fmt.Sprintf("clamp(%s, %s, %s)", lower.Text(), preferred, upper.Text()), nil
+}
@@ -0,0 +1,128 @@
+package convert
+
+import (
+	"testing"
+)
+
+func TestRangeBefore(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		from     float64
+		to       float64
+	}{
+		{"Range", "font-size: 14px..20px", "14px..20px", 14, 20},
+		{"Spaces", "font-size: 14px .. 20.5px", "14px .. 20.5px", 14, 20.5},
+		{"Decreasing", "margin: 0 40px..16px", "40px..16px", 40, 16},
+		{"Single value", "font-size: 20px", "", 0, 0},
+		{"Other unit", "font-size: 1rem..20px", "", 0, 0},
+		{"Not a range", "margin: 14px 20px", "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := RangeBefore(tt.input, len(tt.input))
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no range, got %+v", r)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Expected range %q, got none", tt.expected)
+			}
+			if got := tt.input[r.Start:r.End]; got != tt.expected {
+				t.Errorf("Expected range %q, got %q", tt.expected, got)
+			}
+			if r.From.Value != tt.from || r.To.Value != tt.to {
+				t.Errorf("Expected %v..%v, got %v..%v", tt.from, tt.to, r.From.Value, r.To.Value)
+			}
+		})
+	}
+}
+
+func TestRangeAtAndIn(t *testing.T) {
+	line := "font-size: 14px..20px; margin: 8px 12px"
+
+	if r, ok := RangeAt(line, 12); !ok || line[r.Start:r.End] != "14px..20px" {
+		t.Errorf("RangeAt inside the range: got %+v, %v", r, ok)
+	}
+	if _, ok := RangeAt(line, 32); ok {
+		t.Error("RangeAt on a single value should not match")
+	}
+	if r, ok := RangeIn(line, 0, 21); !ok || r.To.Value != 20 {
+		t.Errorf("RangeIn around the range: got %+v, %v", r, ok)
+	}
+	if r, ok := RangeIn(line, 31, len(line)); !ok || r.From.Value != 8 || r.To.Value != 12 || line[r.Start:r.End] != "8px 12px" {
+		t.Errorf("RangeIn around a pair: got %+v, %v", r, ok)
+	}
+	if _, ok := RangeIn(line, 0, len(line)); ok {
+		t.Error("RangeIn around four values should not match")
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		from, to Match
+		expected string
+	}{
+		{
+			name:     "Growing",
+			config:   Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, RootFontSize: 16},
+			from:     Match{Number: "14", Unit: "px", Value: 14},
+			to:       Match{Number: "20", Unit: "px", Value: 20},
+			expected: "clamp(14px, 0.563vw + 0.743rem, 20px)",
+		},
+		{
+			name:     "Shrinking",
+			config:   Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, RootFontSize: 16},
+			from:     Match{Number: "20", Unit: "px", Value: 20},
+			to:       Match{Number: "14", Unit: "px", Value: 14},
+			expected: "clamp(14px, -0.563vw + 1.382rem, 20px)",
+		},
+		{
+			name:     "Negative offset",
+			config:   Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, RootFontSize: 16},
+			from:     Match{Number: "10", Unit: "px", Value: 10},
+			to:       Match{Number: "100", Unit: "px", Value: 100},
+			expected: "clamp(10px, 8.451vw - 1.356rem, 100px)",
+		},
+		{
+			name:     "Without offset",
+			config:   Config{ViewportWidth: 1000, MinViewportWidth: 500, UnitPrecision: 3},
+			from:     Match{Number: "10", Unit: "px", Value: 10},
+			to:       Match{Number: "20", Unit: "px", Value: 20},
+			expected: "clamp(10px, 2vw, 20px)",
+		},
+		{
+			name: "Breakpoint width",
+			config: Config{ViewportWidth: 1440, MinViewportWidth: 375, UnitPrecision: 3, RootFontSize: 16,
+				Breakpoints: []Breakpoint{{Media: "(min-width: 768px)", ViewportWidth: 1000}}},
+			from:     Match{Number: "16", Unit: "px", Value: 16},
+			to:       Match{Number: "24", Unit: "px", Value: 24, Media: "(min-width: 768px)"},
+			expected: "clamp(16px, 1.28vw + 0.7rem, 24px)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newConverter(t, tt.config).Clamp(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	for _, minWidth := range []float64{0, 1440, 2000} {
+		converter := newConverter(t, Config{ViewportWidth: 1440, MinViewportWidth: minWidth, UnitPrecision: 3})
+		if _, err := converter.Clamp(Match{Value: 14}, Match{Value: 20}); err == nil {
+			t.Errorf("Expected an error for a minimum viewport width of %v", minWidth)
+		}
+	}
+}
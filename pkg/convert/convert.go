@@ -0,0 +1,376 @@
+// Package convert finds px, vw, rem and rpx literals in stylesheets and
+// converts between them. It is the engine behind px-to-vw-lsp and can be
+// used by build tools to get the same results as the editor.
+package convert
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// units are the units literals are found for
+var units = map[string]bool{"px": true, "vw": true, "rem": true, "rpx": true}
+
+// Config holds the settings conversions depend on
+type Config struct {
+	// ViewportWidth is the design width in px that 100vw corresponds to
+	ViewportWidth float64
+	// UnitPrecision is the number of decimals of converted values, zero
+	// rounds them to whole numbers
+	UnitPrecision int
+	// RootFontSize is the root font-size in px used for rem conversion,
+	// zero disables rem conversion
+	RootFontSize float64
+	// Vw enables vw to px conversion
+	Vw bool
+	// Wxss enables px <-> rpx conversion for WeChat mini-programs
+	Wxss            bool
+	WxssDeviceWidth float64
+	WxssScreenWidth float64
+	// AddMark appends a `/* 12px */` comment with the original value to replacements
+	AddMark bool
+	// IgnoresViaCommand are literals left alone by bulk conversion, e.g. "1px"
+	IgnoresViaCommand []string
+	// Breakpoints are the design widths of responsive @media blocks
+	Breakpoints []Breakpoint
+	// MinViewportWidth is the viewport width fluid clamp() sizes start
+	// growing at, up to ViewportWidth
+	MinViewportWidth float64
+	// ConvertConditions also converts literals in @media, @container and
+	// @supports conditions, which are left alone by default
+	ConvertConditions bool
+	// Dialect is the language the rewriting methods tokenize texts as
+	Dialect Dialect
+}
+
+// Breakpoint is the design width of the @media blocks whose condition
+// contains Media, e.g. 375 for "(max-width: 768px)"
+type Breakpoint struct {
+	Media         string
+	ViewportWidth float64
+}
+
+// Converter converts unit literals according to a Config
+type Converter struct {
+	config Config
+}
+
+// New returns a Converter for config, or an error when config can't convert
+// anything: ViewportWidth must be positive and UnitPrecision can't be
+// negative. A zero UnitPrecision rounds converted values to whole numbers.
+func New(config Config) (*Converter, error) {
+	if !(config.ViewportWidth > 0) || math.IsInf(config.ViewportWidth, 1) {
+		return nil, fmt.Errorf("viewport width must be a positive number, got %v", config.ViewportWidth)
+	}
+	if config.UnitPrecision < 0 {
+		return nil, fmt.Errorf("unit precision can't be negative, got %d", config.UnitPrecision)
+	}
+	return &Converter{config: config}, nil
+}
+
+// Match is a unit literal found in text, with byte offsets into the text
+type Match struct {
+	Number string
+	Unit   string
+	Value  float64
+	Start  int
+	End    int
+	// AtRule is the lowercased name of the at-rule whose prelude contains
+	// the literal, e.g. "media", and empty in declarations
+	AtRule string
+	// Media is the condition of the @media blocks enclosing the literal,
+	// nested ones joined with "and", e.g. "screen and (max-width: 768px)"
+	Media string
+	// Property is the lowercased name of the declaration containing the
+	// literal, e.g. "border-width", and empty outside declarations
+	Property string
+	// Bound is set when the literal is either side of a "14px..20px" range,
+	// which only converts as a whole, to a clamp()
+	Bound bool
+}
+
+// Text returns the literal as written, e.g. "12.5px"
+func (m Match) Text() string {
+	return m.Number + m.Unit
+}
+
+// Conversion is a replacement offered for a unit literal
+type Conversion struct {
+	From   Match
+	Number string
+	Unit   string
+}
+
+// Text returns the converted literal, e.g. "0.868vw"
+func (c Conversion) Text() string {
+	return c.Number + c.Unit
+}
+
+// Involves reports whether unit is either side of the conversion
+func (c Conversion) Involves(unit string) bool {
+	return c.From.Unit == unit || c.Unit == unit
+}
+
+// FindUnits returns the px, vw, rem and rpx literals in text, a stylesheet
+// or a fragment of one in the SCSS dialect. Only dimension tokens count, so
+// values in comments, strings, urls and identifiers like `.mt-10px` are
+// skipped, as are those in selectors. NewScan finds them in other dialects.
+func FindUnits(text string) []Match {
+	return NewScan(text, DialectSCSS).Matches
+}
+
+// findUnits returns the unit literals of the tokens of text
+func findUnits(text string, tokens []Token) []Match {
+	var matches []Match
+	// media holds the @media condition of each open block, empty for other blocks
+	var media []string
+	start := 0
+	for i, token := range tokens {
+		switch token.Kind {
+		case TokenOpenBrace, TokenCloseBrace, TokenSemicolon:
+			matches = appendSegmentUnits(matches, tokens[start:i], token.Kind, mediaCondition(media))
+			switch token.Kind {
+			case TokenOpenBrace:
+				condition := ""
+				if block := newBlock(text, tokens[start:i], token, 0); block.AtRule == "media" {
+					condition = strings.TrimSpace(block.Prelude[len("@media"):])
+				}
+				media = append(media, condition)
+			case TokenCloseBrace:
+				if len(media) > 0 {
+					media = media[:len(media)-1]
+				}
+			}
+			start = i + 1
+		}
+	}
+	return appendSegmentUnits(matches, tokens[start:], TokenWhitespace, mediaCondition(media))
+}
+
+// mediaCondition joins the conditions of nested @media blocks, which
+// apply together
+func mediaCondition(media []string) string {
+	var conditions []string
+	for _, condition := range media {
+		if condition != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// appendSegmentUnits appends the literals of a run of tokens ended by
+// terminator, inside @media blocks with the condition media. A run ended
+// by "{" is a selector or an at-rule prelude, any other run is a
+// declaration or an at-rule statement like @include.
+func appendSegmentUnits(matches []Match, segment []Token, terminator TokenKind, media string) []Match {
+	significant := segment[:0:0]
+	for _, token := range segment {
+		if token.Kind != TokenWhitespace && token.Kind != TokenComment {
+			significant = append(significant, token)
+		}
+	}
+	if len(significant) == 0 {
+		return matches
+	}
+
+	atRule, property := "", ""
+	if first := significant[0]; first.Kind == TokenAtKeyword {
+		// a Less variable like `@gutter: 10px` is a declaration
+		if len(significant) < 2 || significant[1].Kind != TokenColon {
+			atRule = strings.ToLower(strings.TrimPrefix(first.Text, "@"))
+		}
+	} else if terminator == TokenOpenBrace {
+		return matches
+	} else if first.Kind == TokenIdent && len(significant) > 1 && significant[1].Kind == TokenColon {
+		property = strings.ToLower(first.Text)
+	}
+
+	for _, token := range significant {
+		if token.Kind != TokenDimension || !units[token.Unit] {
+			continue
+		}
+		value, err := strconv.ParseFloat(token.Number, 64)
+		if err != nil {
+			continue
+		}
+		matches = append(matches, Match{
+			Number:   token.Number,
+			Unit:     token.Unit,
+			Value:    value,
+			Start:    token.Start,
+			End:      token.End,
+			AtRule:   atRule,
+			Media:    media,
+			Property: property,
+		})
+	}
+	return matches
+}
+
+// UnitAt returns the unit literal touching the given byte offset, if any
+func UnitAt(line string, offset int) (Match, bool) {
+	return NewScan(line, DialectSCSS).UnitAt(offset)
+}
+
+// UnitBefore returns the unit literal ending exactly at the given byte
+// offset, if any. Only the text before the offset is read, like when typing.
+func UnitBefore(line string, offset int) (Match, bool) {
+	offset = min(max(offset, 0), len(line))
+	return NewScan(line[:offset], DialectSCSS).UnitBefore(offset)
+}
+
+// conditionAtRules are the at-rules whose preludes are conditions, where
+// px values are thresholds rather than sizes
+var conditionAtRules = map[string]bool{"media": true, "container": true, "supports": true}
+
+// Excluded reports whether no conversion applies to match on its own: it is
+// a range bound, or in an @media, @container or @supports condition and
+// ConvertConditions isn't set
+func (c *Converter) Excluded(match Match) bool {
+	return match.Bound || conditionAtRules[match.AtRule] && !c.config.ConvertConditions
+}
+
+// Conversions returns the conversions enabled by the config for a unit literal
+func (c *Converter) Conversions(match Match) []Conversion {
+	if c.Excluded(match) {
+		return nil
+	}
+	config := c.config
+	var conversions []Conversion
+	switch match.Unit {
+	case "px":
+		if config.Wxss && config.WxssDeviceWidth != 0 {
+			conversions = append(conversions, Conversion{From: match, Number: c.PxToRpx(match.Value), Unit: "rpx"})
+		}
+		conversions = append(conversions, c.PxToVwConversion(match))
+		if config.RootFontSize != 0 {
+			conversions = append(conversions, Conversion{From: match, Number: c.PxToRem(match.Value), Unit: "rem"})
+		}
+	case "rem":
+		if config.RootFontSize != 0 {
+			conversions = append(conversions, Conversion{From: match, Number: c.RemToPx(match.Value), Unit: "px"})
+		}
+	case "rpx":
+		if config.Wxss && config.WxssScreenWidth != 0 {
+			conversions = append(conversions, Conversion{From: match, Number: c.RpxToPx(match.Value), Unit: "px"})
+		}
+	case "vw":
+		if config.Vw {
+			conversions = append(conversions, c.VwToPxConversion(match))
+		}
+	}
+	return conversions
+}
+
+// PxToVwConversion returns the vw conversion of a px literal
+func (c *Converter) PxToVwConversion(match Match) Conversion {
+	return Conversion{From: match, Number: formatVw(match.Value, c.ViewportWidth(match), c.config.UnitPrecision), Unit: "vw"}
+}
+
+// VwToPxConversion returns the px conversion of a vw literal
+func (c *Converter) VwToPxConversion(match Match) Conversion {
+	return Conversion{From: match, Number: formatTrimmed(match.Value*c.ViewportWidth(match)/100, c.config.UnitPrecision), Unit: "px"}
+}
+
+// ViewportWidth returns the design width vw literals of match are relative
+// to: that of the first breakpoint whose condition is part of the enclosing
+// @media condition, or the configured ViewportWidth
+func (c *Converter) ViewportWidth(match Match) float64 {
+	if match.Media == "" {
+		return c.config.ViewportWidth
+	}
+	media := normalizeMedia(match.Media)
+	for _, breakpoint := range c.config.Breakpoints {
+		if breakpoint.ViewportWidth > 0 && strings.Contains(media, normalizeMedia(breakpoint.Media)) {
+			return breakpoint.ViewportWidth
+		}
+	}
+	return c.config.ViewportWidth
+}
+
+// normalizeMedia lowercases a media condition and drops its whitespace, so
+// "(max-width:768px)" and "(MAX-WIDTH: 768px)" compare equal
+func normalizeMedia(media string) string {
+	return strings.Join(strings.Fields(strings.ToLower(media)), "")
+}
+
+// Convert converts a single literal such as "348px" to unit, e.g. "vw"
+func (c *Converter) Convert(literal, unit string) (string, error) {
+	literal = strings.TrimSpace(literal)
+	match, ok := UnitBefore(literal, len(literal))
+	if !ok || match.Start != 0 {
+		return "", fmt.Errorf("not a unit literal: %q", literal)
+	}
+	for _, conv := range c.Conversions(match) {
+		if conv.Unit == unit {
+			return conv.Text(), nil
+		}
+	}
+	return "", fmt.Errorf("no conversion from %s to %s", match.Unit, unit)
+}
+
+// PxToVw converts a px value to a formatted vw number (without unit)
+func (c *Converter) PxToVw(px float64) string {
+	return formatVw(px, c.config.ViewportWidth, c.config.UnitPrecision)
+}
+
+func formatVw(px, viewportWidth float64, precision int) string {
+	return strconv.FormatFloat(px/viewportWidth*100, 'f', precision, 64)
+}
+
+// VwToPx converts a vw value to a formatted px number (without unit)
+func (c *Converter) VwToPx(vw float64) string {
+	return formatTrimmed(vw*c.config.ViewportWidth/100, c.config.UnitPrecision)
+}
+
+// PxToRem converts a px value to a formatted rem number (without unit)
+func (c *Converter) PxToRem(px float64) string {
+	return formatTrimmed(px/c.config.RootFontSize, c.config.UnitPrecision)
+}
+
+// RemToPx converts a rem value to a formatted px number (without unit)
+func (c *Converter) RemToPx(rem float64) string {
+	return formatTrimmed(rem*c.config.RootFontSize, c.config.UnitPrecision)
+}
+
+// PxToRpx converts a px value to a formatted rpx number (without unit),
+// using the same screen/device width ratio as cssrem
+func (c *Converter) PxToRpx(px float64) string {
+	return formatTrimmed(px*c.config.WxssScreenWidth/c.config.WxssDeviceWidth, c.config.UnitPrecision)
+}
+
+// RpxToPx converts an rpx value to a formatted px number (without unit)
+func (c *Converter) RpxToPx(rpx float64) string {
+	return formatTrimmed(rpx*c.config.WxssDeviceWidth/c.config.WxssScreenWidth, c.config.UnitPrecision)
+}
+
+// formatTrimmed rounds f to precision digits and drops trailing zeros,
+// so 12.5vw at 1440 becomes "180" rather than "180.000"
+func formatTrimmed(f float64, precision int) string {
+	scale := math.Pow(10, float64(precision))
+	return strconv.FormatFloat(math.Round(f*scale)/scale, 'f', -1, 64)
+}
+
+// Ignored reports whether match is listed in IgnoresViaCommand
+func (c *Converter) Ignored(match Match) bool {
+	for _, ignore := range c.config.IgnoresViaCommand {
+		ignore = strings.TrimSpace(ignore)
+		ignored, ok := UnitBefore(ignore, len(ignore))
+		if ok && ignored.Start == 0 && ignored.Unit == match.Unit && ignored.Value == match.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Replacement returns the text a conversion writes into the buffer,
+// followed by a `/* 12px */` mark when AddMark is enabled
+func (c *Converter) Replacement(conv Conversion) string {
+	if c.config.AddMark {
+		return conv.Text() + " /* " + conv.From.Text() + " */"
+	}
+	return conv.Text()
+}
@@ -0,0 +1,485 @@
+package convert
+
+import (
+	"testing"
+)
+
+// newConverter returns the Converter for config, failing the test when config is invalid
+func newConverter(t *testing.T, config Config) *Converter {
+	t.Helper()
+	converter, err := New(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return converter
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{"Zero config", Config{}, true},
+		{"Negative viewport width", Config{ViewportWidth: -1440, UnitPrecision: 3}, true},
+		{"Negative precision", Config{ViewportWidth: 1440, UnitPrecision: -1}, true},
+		{"Zero precision", Config{ViewportWidth: 1440}, false},
+		{"Valid config", Config{ViewportWidth: 1440, UnitPrecision: 3}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter, err := New(tt.config)
+			if tt.expectError && (err == nil || converter != nil) {
+				t.Errorf("Expected an error, got %v", converter)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPxToVw(t *testing.T) {
+	tests := []struct {
+		name          string
+		pxValue       float64
+		viewportWidth float64
+		precision     int
+		expectedVw    string
+	}{
+		{
+			name:          "Basic conversion 1440px to vw",
+			pxValue:       1440,
+			viewportWidth: 1440,
+			precision:     3,
+			expectedVw:    "100.000",
+		},
+		{
+			name:          "Half viewport width",
+			pxValue:       720,
+			viewportWidth: 1440,
+			precision:     3,
+			expectedVw:    "50.000",
+		},
+		{
+			name:          "Quarter viewport width",
+			pxValue:       360,
+			viewportWidth: 1440,
+			precision:     3,
+			expectedVw:    "25.000",
+		},
+		{
+			name:          "Decimal px value",
+			pxValue:       1536,
+			viewportWidth: 1440,
+			precision:     3,
+			expectedVw:    "106.667",
+		},
+		{
+			name:          "Small px value",
+			pxValue:       16,
+			viewportWidth: 1440,
+			precision:     3,
+			expectedVw:    "1.111",
+		},
+		{
+			name:          "Different viewport 1920",
+			pxValue:       1920,
+			viewportWidth: 1920,
+			precision:     3,
+			expectedVw:    "100.000",
+		},
+		{
+			name:          "Precision 2",
+			pxValue:       100,
+			viewportWidth: 1440,
+			precision:     2,
+			expectedVw:    "6.94",
+		},
+		{
+			name:          "Precision 1",
+			pxValue:       100,
+			viewportWidth: 1440,
+			precision:     1,
+			expectedVw:    "6.9",
+		},
+		{
+			name:          "Zero precision (rounded)",
+			pxValue:       100,
+			viewportWidth: 1440,
+			precision:     0,
+			expectedVw:    "7",
+		},
+		{
+			name:          "Large viewport width",
+			pxValue:       1536,
+			viewportWidth: 2560,
+			precision:     3,
+			expectedVw:    "60.000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := newConverter(t, Config{ViewportWidth: tt.viewportWidth, UnitPrecision: tt.precision})
+			if result := converter.PxToVw(tt.pxValue); result != tt.expectedVw {
+				t.Errorf("%fpx at viewport %f with precision %d = %svw (expected %svw)",
+					tt.pxValue, tt.viewportWidth, tt.precision, result, tt.expectedVw)
+			}
+		})
+	}
+}
+
+func TestFindUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Simple px value", "width: 100px", []string{"100px"}},
+		{"Decimal px value", "width: 100.5px", []string{"100.5px"}},
+		{"Media query", "@media (min-width: 768px)", []string{"768px"}},
+		{"Negative px value", "margin: -20px", []string{"-20px"}},
+		{"No px value", "width: 100%", nil},
+		{"Multiple px values", "margin: 10px 20px", []string{"10px", "20px"}},
+		{"Multiple units", "margin: 10px 2rem 5vw 20rpx", []string{"10px", "2rem", "5vw", "20rpx"}},
+		{"Em value", "font-size: 16em", nil},
+		{"Leading dot", "width: .5px", []string{".5px"}},
+		{"Longer unit", "width: 10pxs", nil},
+		{"Bare literal", "348px", []string{"348px"}},
+		{"Comment", "width: 10px; /* was 12px */", []string{"10px"}},
+		{"Comment across lines", "/*\n  width: 12px;\n*/\nwidth: 10px;", []string{"10px"}},
+		{"SCSS line comment", "width: 10px; // was 12px", []string{"10px"}},
+		{"String", `content: "10px"; width: 20px`, []string{"20px"}},
+		{"Url", "background: url(icon-24px.svg) 0 2px", []string{"2px"}},
+		{"Quoted url", `background: url("icon-24px.svg")`, nil},
+		{"Class with a px suffix", ".mt-10px { margin-top: 10px; }", []string{"10px"}},
+		{"Id", "#w-10px { width: 10px }", []string{"10px"}},
+		{"Selector", "li:nth-child(2px) { width: 10px }", []string{"10px"}},
+		{"Nested rule", ".a { width: 1px; &:hover { width: 2px } }", []string{"1px", "2px"}},
+		{"SCSS variable", "$gutter: 16px;", []string{"16px"}},
+		{"Less variable", "@gutter: 16px;", []string{"16px"}},
+		{"Interpolation", "width: calc(#{$a} + 10px)", []string{"10px"}},
+		{"Media query with a rule", "@media (min-width: 768px) { .a { width: 10px } }", []string{"768px", "10px"}},
+		{"Mixin", "@include size(10px);", []string{"10px"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := FindUnits(tt.input)
+			if len(matches) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, matches)
+			}
+			for i, match := range matches {
+				if match.Text() != tt.expected[i] {
+					t.Errorf("Match %d: got %q, want %q", i, match.Text(), tt.expected[i])
+				}
+				if tt.input[match.Start:match.End] != match.Text() {
+					t.Errorf("Match %d offsets %d-%d don't cover %q", i, match.Start, match.End, match.Text())
+				}
+			}
+		})
+	}
+}
+
+func TestFindUnitsAtRule(t *testing.T) {
+	matches := FindUnits("@MEDIA (min-width: 768px) { .a { width: 10px } }\n@gutter: 4px;")
+	expected := []string{"media", "", ""}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %d matches, got %v", len(expected), matches)
+	}
+	for i, match := range matches {
+		if match.AtRule != expected[i] {
+			t.Errorf("%s: got at-rule %q, want %q", match.Text(), match.AtRule, expected[i])
+		}
+	}
+}
+
+func TestFindUnitsMedia(t *testing.T) {
+	text := "@media screen and (max-width: 768px) {\n  .a { width: 10px; @media (orientation: landscape) { height: 5px } }\n  margin: 2px;\n}\n.b { width: 1px }"
+	expected := map[string]string{
+		"768px": "",
+		"10px":  "screen and (max-width: 768px)",
+		"5px":   "screen and (max-width: 768px) and (orientation: landscape)",
+		"2px":   "screen and (max-width: 768px)",
+		"1px":   "",
+	}
+	matches := FindUnits(text)
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %d matches, got %v", len(expected), matches)
+	}
+	for _, match := range matches {
+		if match.Media != expected[match.Text()] {
+			t.Errorf("%s: got media %q, want %q", match.Text(), match.Media, expected[match.Text()])
+		}
+	}
+}
+
+func TestFindUnitsProperty(t *testing.T) {
+	tests := []struct {
+		text   string
+		expect string
+	}{
+		{"  width: 10px;", "width"},
+		{".a { Border-Width: 2px; }", "border-width"},
+		{"a { margin: 0; padding: 4px }", "padding"},
+		{"  border-width:\n    1px;", "border-width"},
+		{"    10px 20px;", ""},
+		{"@gutter: 4px;", ""},
+	}
+
+	for _, tt := range tests {
+		matches := FindUnits(tt.text)
+		if len(matches) == 0 {
+			t.Fatalf("%q: expected a match", tt.text)
+		}
+		if got := matches[0].Property; got != tt.expect {
+			t.Errorf("%q: got property %q, want %q", tt.text, got, tt.expect)
+		}
+	}
+}
+
+func TestViewportWidth(t *testing.T) {
+	converter := newConverter(t, Config{
+		ViewportWidth: 1440,
+		UnitPrecision: 3,
+		Breakpoints: []Breakpoint{
+			{Media: "(max-width: 768px)", ViewportWidth: 375},
+			{Media: "(MAX-WIDTH:1024px)", ViewportWidth: 768},
+		},
+	})
+
+	tests := []struct {
+		media    string
+		expected float64
+	}{
+		{"", 1440},
+		{"(max-width: 768px)", 375},
+		{"screen and (max-width:768px) and (orientation: landscape)", 375},
+		{"(max-width: 1024px)", 768},
+		{"(max-width: 7680px)", 1440},
+		{"print", 1440},
+	}
+	for _, tt := range tests {
+		if got := converter.ViewportWidth(Match{Media: tt.media}); got != tt.expected {
+			t.Errorf("%q: got %v, want %v", tt.media, got, tt.expected)
+		}
+	}
+
+	text := ".a { width: 144px }\n@media (max-width: 768px) { .a { width: 75px; left: 10vw } }"
+	converted := map[string]string{}
+	for _, edit := range converter.PxToVwEdits(text) {
+		converted[edit.Conversion.From.Text()] = edit.NewText
+	}
+	if converted["144px"] != "10.000vw" || converted["75px"] != "20.000vw" {
+		t.Errorf("Expected 144px at 1440 and 75px at 375, got %v", converted)
+	}
+	if edits := converter.VwToPxEdits(text); len(edits) != 1 || edits[0].NewText != "37.5px" {
+		t.Errorf("Expected 10vw at 375 to be 37.5px, got %v", edits)
+	}
+}
+
+func TestUnitAtAndBefore(t *testing.T) {
+	line := "margin: 10px 20px"
+
+	if match, ok := UnitAt(line, 10); !ok || match.Text() != "10px" {
+		t.Errorf("UnitAt inside 10px: got %v, %v", match, ok)
+	}
+	if _, ok := UnitAt(line, 3); ok {
+		t.Error("UnitAt in the property name should not match")
+	}
+	if match, ok := UnitBefore(line, len(line)); !ok || match.Text() != "20px" {
+		t.Errorf("UnitBefore at the end: got %v, %v", match, ok)
+	}
+	if _, ok := UnitBefore(line, 11); ok {
+		t.Error("UnitBefore inside a literal should not match")
+	}
+	if _, ok := UnitBefore(line, 100); !ok {
+		t.Error("UnitBefore past the end should clamp to the line")
+	}
+}
+
+func TestConversions(t *testing.T) {
+	converter := newConverter(t, Config{
+		ViewportWidth:   1440,
+		UnitPrecision:   3,
+		RootFontSize:    16,
+		Vw:              true,
+		Wxss:            true,
+		WxssDeviceWidth: 375,
+		WxssScreenWidth: 750,
+	})
+
+	tests := []struct {
+		literal  string
+		expected []string
+	}{
+		{"348px", []string{"696rpx", "24.167vw", "21.75rem"}},
+		{"2rem", []string{"32px"}},
+		{"100rpx", []string{"50px"}},
+		{"12.5vw", []string{"180px"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.literal, func(t *testing.T) {
+			conversions := converter.Conversions(FindUnits(tt.literal)[0])
+			if len(conversions) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, conversions)
+			}
+			for i, conv := range conversions {
+				if conv.Text() != tt.expected[i] {
+					t.Errorf("Conversion %d: got %q, want %q", i, conv.Text(), tt.expected[i])
+				}
+			}
+		})
+	}
+
+	// rem, rpx and vw to px are off without their settings
+	plain := newConverter(t, Config{ViewportWidth: 1440, UnitPrecision: 3})
+	for _, literal := range []string{"2rem", "100rpx", "12.5vw"} {
+		if conversions := plain.Conversions(FindUnits(literal)[0]); len(conversions) != 0 {
+			t.Errorf("Expected no conversions of %s, got %v", literal, conversions)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	converter := newConverter(t, Config{ViewportWidth: 1440, UnitPrecision: 3, RootFontSize: 16})
+
+	tests := []struct {
+		literal   string
+		unit      string
+		expected  string
+		expectErr bool
+	}{
+		{"348px", "vw", "24.167vw", false},
+		{" 32px ", "rem", "2rem", false},
+		{"2rem", "px", "32px", false},
+		{"12vw", "px", "", true},
+		{"width: 10px", "vw", "", true},
+		{"10", "vw", "", true},
+	}
+
+	for _, tt := range tests {
+		result, err := converter.Convert(tt.literal, tt.unit)
+		if tt.expectErr {
+			if err == nil {
+				t.Errorf("Convert(%q, %q): expected an error, got %q", tt.literal, tt.unit, result)
+			}
+			continue
+		}
+		if err != nil || result != tt.expected {
+			t.Errorf("Convert(%q, %q) = %q, %v, want %q", tt.literal, tt.unit, result, err, tt.expected)
+		}
+	}
+}
+
+func TestConvertText(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        Config
+		input         string
+		expected      string
+		expectedCount int
+	}{
+		{
+			name:          "Every px",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3},
+			input:         ".a {\n  width: 348px;\n  margin: 10px 2rem;\n}",
+			expected:      ".a {\n  width: 24.167vw;\n  margin: 0.694vw 2rem;\n}",
+			expectedCount: 2,
+		},
+		{
+			name:          "Ignored values",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, IgnoresViaCommand: []string{"1px"}},
+			input:         "border: 1px solid; width: 144px;",
+			expected:      "border: 1px solid; width: 10.000vw;",
+			expectedCount: 1,
+		},
+		{
+			name:          "Conditions",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3},
+			input:         "@media (min-width: 720px) { .a { width: 144px } }\n@container (width > 360px) {}\n@supports (top: 1px) {}",
+			expected:      "@media (min-width: 720px) { .a { width: 10.000vw } }\n@container (width > 360px) {}\n@supports (top: 1px) {}",
+			expectedCount: 1,
+		},
+		{
+			name:          "Converted conditions",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, ConvertConditions: true},
+			input:         "@media (min-width: 720px) { .a { width: 144px } }",
+			expected:      "@media (min-width: 50.000vw) { .a { width: 10.000vw } }",
+			expectedCount: 2,
+		},
+		{
+			name:          "Line comment",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3},
+			input:         "width: 144px; // 72px",
+			expected:      "width: 10.000vw; // 72px",
+			expectedCount: 1,
+		},
+		{
+			name:          "Slashes in CSS",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, Dialect: DialectCSS},
+			input:         "width: 144px; // 72px",
+			expected:      "width: 10.000vw; // 5.000vw",
+			expectedCount: 2,
+		},
+		{
+			name:          "Range",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3},
+			input:         "font-size: 14px..20px; width: 144px;",
+			expected:      "font-size: 14px..20px; width: 10.000vw;",
+			expectedCount: 1,
+		},
+		{
+			name:          "Sass conditions",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, Dialect: DialectSass},
+			input:         "@media (max-width: 768px)\n  .a\n    width: 144px // 72px",
+			expected:      "@media (max-width: 768px)\n  .a\n    width: 10.000vw // 72px",
+			expectedCount: 1,
+		},
+		{
+			name:          "Marks",
+			config:        Config{ViewportWidth: 1440, UnitPrecision: 3, AddMark: true},
+			input:         "width: 144px;",
+			expected:      "width: 10.000vw /* 144px */;",
+			expectedCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, count := newConverter(t, tt.config).ConvertText(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+			if count != tt.expectedCount {
+				t.Errorf("Expected %d conversions, got %d", tt.expectedCount, count)
+			}
+		})
+	}
+}
+
+func TestConvertTextToPx(t *testing.T) {
+	converter := newConverter(t, Config{ViewportWidth: 1440, UnitPrecision: 3, IgnoresViaCommand: []string{"100vw"}})
+	result, count := converter.ConvertTextToPx("width: 10vw; max-width: 100vw; margin: 0.694vw 2px;")
+	if expected := "width: 144px; max-width: 100vw; margin: 9.994px 2px;"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 conversions, got %d", count)
+	}
+}
+
+func TestApply(t *testing.T) {
+	text := "abcdef"
+	edits := []Edit{
+		{Start: 4, End: 5, NewText: "E"},
+		{Start: 0, End: 1, NewText: "AA"},
+		{Start: 2, End: 2, NewText: "-"},
+	}
+	if result := Apply(text, edits); result != "AAb-cdEf" {
+		t.Errorf("Expected %q, got %q", "AAb-cdEf", result)
+	}
+}
@@ -0,0 +1,446 @@
+package convert
+
+import (
+	"slices"
+	"strings"
+)
+
+// TokenKind classifies a token of a stylesheet
+type TokenKind int
+
+const (
+	TokenWhitespace TokenKind = iota
+	TokenComment
+	TokenString
+	TokenURL
+	TokenIdent
+	// TokenFunction is a name followed by "(", e.g. "calc("
+	TokenFunction
+	TokenAtKeyword
+	TokenHash
+	// TokenInterpolation is SCSS `#{...}` or Less `@{...}`
+	TokenInterpolation
+	TokenNumber
+	TokenPercentage
+	TokenDimension
+	TokenColon
+	TokenSemicolon
+	TokenComma
+	TokenOpenBrace
+	TokenCloseBrace
+	TokenOpenParen
+	TokenCloseParen
+	TokenDelim
+)
+
+// Token is a token of a stylesheet, with byte offsets into the text
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Start int
+	End   int
+	// Number is the numeric part of numbers, percentages and dimensions,
+	// Unit the unit of dimensions, e.g. "12.5" and "px"
+	Number string
+	Unit   string
+}
+
+// Dialect is the stylesheet language a text is tokenized as
+type Dialect int
+
+const (
+	// DialectSCSS is SCSS, Less and other languages with `//` line
+	// comments. It is the default.
+	DialectSCSS Dialect = iota
+	// DialectCSS is plain CSS, where `//` doesn't start a comment
+	DialectCSS
+	// DialectSass is the indented syntax of Sass and Stylus, where lines and
+	// their indentation stand in for ";", "{" and "}"
+	DialectSass
+)
+
+// DialectFor returns the dialect of an editor language id like "css" or
+// "scss". Unknown languages, e.g. the style blocks of "vue", are SCSS.
+func DialectFor(language string) Dialect {
+	switch strings.ToLower(language) {
+	case "css", "wxss":
+		return DialectCSS
+	case "sass", "stylus":
+		return DialectSass
+	}
+	return DialectSCSS
+}
+
+// Tokenize splits a CSS, SCSS or Less stylesheet into tokens. Besides CSS
+// comments it understands interpolation and, unless the dialect is CSS,
+// `//` line comments, so neither is mistaken for values. Unterminated
+// comments and strings run to the end of the text, as in a buffer being
+// edited. In the Sass dialect the ";", "{" and "}" implied by lines and
+// their indentation are added as empty tokens.
+func Tokenize(text string, dialect Dialect) []Token {
+	l := lexer{text: text, lineComments: dialect != DialectCSS}
+	var tokens []Token
+	for l.pos < len(text) {
+		tokens = append(tokens, l.next())
+	}
+	if dialect == DialectSass {
+		tokens = indentTokens(text, tokens)
+	}
+	return tokens
+}
+
+// indentTokens adds the tokens the indented syntax leaves out, at the end of
+// the line before: a line indented deeper than the one before opens a
+// block, one indented the same ends a statement and one indented less also
+// closes the blocks it is outside of. Lines after a "," or inside
+// parentheses continue the one before, and the braces Stylus allows to be
+// written out are left to themselves.
+func indentTokens(text string, tokens []Token) []Token {
+	type level struct {
+		indent int
+		// implied is set when the block was opened by indentation alone
+		implied bool
+	}
+	empty := func(kind TokenKind, offset int) Token {
+		return Token{Kind: kind, Start: offset, End: offset}
+	}
+
+	var result []Token
+	var levels []level
+	// last is the index in result of the last token that isn't whitespace or a comment
+	last := -1
+	parens := 0
+	for _, token := range tokens {
+		if token.Kind == TokenWhitespace || token.Kind == TokenComment {
+			result = append(result, token)
+			continue
+		}
+
+		if last < 0 {
+			levels = append(levels, level{indent: indentation(text, token.Start)})
+		} else if prev := result[last]; parens == 0 && prev.Kind != TokenComma && strings.Contains(text[prev.End:token.Start], "\n") {
+			indent := indentation(text, token.Start)
+			var implied []Token
+			if indent > levels[len(levels)-1].indent {
+				levels = append(levels, level{indent: indent, implied: prev.Kind != TokenOpenBrace})
+				if prev.Kind != TokenOpenBrace {
+					implied = append(implied, empty(TokenOpenBrace, prev.End))
+				}
+			} else {
+				if prev.Kind != TokenOpenBrace && prev.Kind != TokenSemicolon && prev.Kind != TokenCloseBrace {
+					implied = append(implied, empty(TokenSemicolon, prev.End))
+				}
+				for len(levels) > 1 && levels[len(levels)-1].indent > indent {
+					if levels[len(levels)-1].implied {
+						implied = append(implied, empty(TokenCloseBrace, prev.End))
+					}
+					levels = levels[:len(levels)-1]
+				}
+			}
+			result = slices.Insert(result, last+1, implied...)
+		}
+
+		switch token.Kind {
+		case TokenFunction, TokenOpenParen:
+			parens++
+		case TokenCloseParen:
+			parens = max(parens-1, 0)
+		}
+		last = len(result)
+		result = append(result, token)
+	}
+
+	// blocks still open are closed at the end of the last line
+	var implied []Token
+	for len(levels) > 1 {
+		if levels[len(levels)-1].implied {
+			implied = append(implied, empty(TokenCloseBrace, result[last].End))
+		}
+		levels = levels[:len(levels)-1]
+	}
+	return slices.Insert(result, last+1, implied...)
+}
+
+// indentation returns the number of spaces and tabs starting the line that
+// contains offset
+func indentation(text string, offset int) int {
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	width := 0
+	for width < offset-start && (text[start+width] == ' ' || text[start+width] == '\t') {
+		width++
+	}
+	return width
+}
+
+type lexer struct {
+	text         string
+	pos          int
+	lineComments bool
+}
+
+// at returns the byte i positions ahead, or 0 past the end
+func (l *lexer) at(i int) byte {
+	if l.pos+i < len(l.text) {
+		return l.text[l.pos+i]
+	}
+	return 0
+}
+
+func (l *lexer) next() Token {
+	start := l.pos
+	token := func(kind TokenKind) Token {
+		return Token{Kind: kind, Text: l.text[start:l.pos], Start: start, End: l.pos}
+	}
+
+	c := l.at(0)
+	switch {
+	case isWhitespace(c):
+		for l.pos < len(l.text) && isWhitespace(l.at(0)) {
+			l.pos++
+		}
+		return token(TokenWhitespace)
+
+	case c == '/' && l.at(1) == '*':
+		if end := strings.Index(l.text[l.pos+2:], "*/"); end >= 0 {
+			l.pos += 2 + end + 2
+		} else {
+			l.pos = len(l.text)
+		}
+		return token(TokenComment)
+
+	case c == '/' && l.at(1) == '/' && l.lineComments:
+		if end := strings.IndexByte(l.text[l.pos:], '\n'); end >= 0 {
+			l.pos += end
+		} else {
+			l.pos = len(l.text)
+		}
+		return token(TokenComment)
+
+	case c == '"' || c == '\'':
+		l.consumeString(c)
+		return token(TokenString)
+
+	case (c == '#' || c == '@') && l.at(1) == '{':
+		l.consumeInterpolation()
+		return token(TokenInterpolation)
+
+	case l.startsNumber():
+		number := l.consumeNumber()
+		switch {
+		case l.at(0) == '%':
+			l.pos++
+			t := token(TokenPercentage)
+			t.Number = number
+			return t
+		case l.startsIdent(0):
+			unitStart := l.pos
+			l.consumeIdent()
+			t := token(TokenDimension)
+			t.Number, t.Unit = number, l.text[unitStart:l.pos]
+			return t
+		}
+		t := token(TokenNumber)
+		t.Number = number
+		return t
+
+	case l.startsIdent(0):
+		l.consumeIdent()
+		if l.at(0) != '(' {
+			return token(TokenIdent)
+		}
+		if strings.EqualFold(l.text[start:l.pos], "url") && l.consumeURL() {
+			return token(TokenURL)
+		}
+		l.pos++
+		return token(TokenFunction)
+
+	case c == '@' && l.startsIdent(1):
+		l.pos++
+		l.consumeIdent()
+		return token(TokenAtKeyword)
+
+	case c == '#' && isIdentChar(l.at(1)):
+		l.pos++
+		for l.pos < len(l.text) && isIdentChar(l.at(0)) {
+			l.pos++
+		}
+		return token(TokenHash)
+	}
+
+	l.pos++
+	switch c {
+	case ':':
+		return token(TokenColon)
+	case ';':
+		return token(TokenSemicolon)
+	case ',':
+		return token(TokenComma)
+	case '{':
+		return token(TokenOpenBrace)
+	case '}':
+		return token(TokenCloseBrace)
+	case '(':
+		return token(TokenOpenParen)
+	case ')':
+		return token(TokenCloseParen)
+	}
+	return token(TokenDelim)
+}
+
+// consumeString consumes a string up to its closing quote, or to the end
+// of the line when it is unterminated
+func (l *lexer) consumeString(quote byte) {
+	l.pos++
+	for l.pos < len(l.text) {
+		switch l.at(0) {
+		case quote:
+			l.pos++
+			return
+		case '\n':
+			return
+		case '\\':
+			l.pos++
+		}
+		l.pos++
+	}
+	l.pos = min(l.pos, len(l.text))
+}
+
+// consumeInterpolation consumes an interpolation with the name characters
+// around it, as in `.col-#{$i}-10px`, which are part of the same name
+func (l *lexer) consumeInterpolation() {
+	for {
+		l.consumeBlock()
+		for isIdentChar(l.at(0)) {
+			l.pos++
+		}
+		if c := l.at(0); (c != '#' && c != '@') || l.at(1) != '{' {
+			return
+		}
+	}
+}
+
+// consumeBlock consumes a block up to its matching "}"
+func (l *lexer) consumeBlock() {
+	depth := 0
+	for l.pos < len(l.text) {
+		switch l.at(0) {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				l.pos++
+				return
+			}
+		}
+		l.pos++
+	}
+}
+
+// consumeURL consumes the rest of an unquoted url(...) after its name.
+// A quoted url is left to be read as a function followed by a string.
+func (l *lexer) consumeURL() bool {
+	i := 1
+	for isWhitespace(l.at(i)) {
+		i++
+	}
+	if c := l.at(i); c == '"' || c == '\'' {
+		return false
+	}
+	if end := strings.IndexByte(l.text[l.pos:], ')'); end >= 0 {
+		l.pos += end + 1
+	} else {
+		l.pos = len(l.text)
+	}
+	return true
+}
+
+// startsNumber reports whether a number starts at the current position
+func (l *lexer) startsNumber() bool {
+	i := 0
+	if c := l.at(0); c == '+' || c == '-' {
+		i++
+	}
+	if c := l.at(i); c == '.' {
+		i++
+	}
+	return isDigit(l.at(i))
+}
+
+func (l *lexer) consumeNumber() string {
+	start := l.pos
+	if c := l.at(0); c == '+' || c == '-' {
+		l.pos++
+	}
+	l.consumeDigits()
+	if l.at(0) == '.' && isDigit(l.at(1)) {
+		l.pos++
+		l.consumeDigits()
+	}
+	// an exponent, but not the "e" of a unit like "em"
+	if c := l.at(0); c == 'e' || c == 'E' {
+		if isDigit(l.at(1)) {
+			l.pos++
+			l.consumeDigits()
+		} else if s := l.at(1); (s == '+' || s == '-') && isDigit(l.at(2)) {
+			l.pos += 2
+			l.consumeDigits()
+		}
+	}
+	return l.text[start:l.pos]
+}
+
+func (l *lexer) consumeDigits() {
+	for isDigit(l.at(0)) {
+		l.pos++
+	}
+}
+
+// startsIdent reports whether an identifier starts i bytes ahead
+func (l *lexer) startsIdent(i int) bool {
+	if l.at(i) == '-' {
+		i++
+		if l.at(i) == '-' {
+			return true
+		}
+	}
+	return isIdentStart(l.at(i)) || l.startsEscape(i)
+}
+
+// startsEscape reports whether a backslash escape starts i bytes ahead
+func (l *lexer) startsEscape(i int) bool {
+	return l.at(i) == '\\' && l.at(i+1) != '\n' && l.at(i+1) != 0
+}
+
+func (l *lexer) consumeIdent() {
+	for l.pos < len(l.text) {
+		c := l.at(0)
+		switch {
+		case l.startsEscape(0):
+			l.pos += 2
+		case isIdentChar(c):
+			l.pos++
+		default:
+			return
+		}
+	}
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentStart reports whether c can start an identifier. Bytes of
+// multibyte UTF-8 sequences are all >= 0x80.
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '-'
+}
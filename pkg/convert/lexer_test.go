@@ -0,0 +1,154 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []TokenKind
+	}{
+		{"Declaration", "width:10px;", []TokenKind{TokenIdent, TokenColon, TokenDimension, TokenSemicolon}},
+		{"Comment", "/* 10px */", []TokenKind{TokenComment}},
+		{"Line comment", "// 10px\n", []TokenKind{TokenComment, TokenWhitespace}},
+		{"Unterminated comment", "/* 10px", []TokenKind{TokenComment}},
+		{"String with escaped quote", `"a\"10px"`, []TokenKind{TokenString}},
+		{"Unterminated string ends at the line", "'10px\n1px", []TokenKind{TokenString, TokenWhitespace, TokenDimension}},
+		{"Unquoted url", "url(icon-24px.svg)", []TokenKind{TokenURL}},
+		{"Protocol relative url", "url(//cdn/24px.svg)", []TokenKind{TokenURL}},
+		{"Quoted url", `url( "24px.svg")`, []TokenKind{TokenFunction, TokenWhitespace, TokenString, TokenCloseParen}},
+		{"Function", "calc(1px)", []TokenKind{TokenFunction, TokenDimension, TokenCloseParen}},
+		{"Identifier with digits", ".mt-10px", []TokenKind{TokenDelim, TokenIdent}},
+		{"Hash", "#fff #10px", []TokenKind{TokenHash, TokenWhitespace, TokenHash}},
+		{"At-keyword", "@media", []TokenKind{TokenAtKeyword}},
+		{"SCSS interpolation", "#{$a + 10px}px", []TokenKind{TokenInterpolation}},
+		{"Less interpolation", "@{prefix}-10px@{suffix} 1px", []TokenKind{TokenInterpolation, TokenWhitespace, TokenDimension}},
+		{"Percentage", "50%", []TokenKind{TokenPercentage}},
+		{"Number", "1.5", []TokenKind{TokenNumber}},
+		{"Custom property", "--gap:4px", []TokenKind{TokenIdent, TokenColon, TokenDimension}},
+		{"Trailing backslash", `a\`, []TokenKind{TokenIdent, TokenDelim}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := Tokenize(tt.input, DialectSCSS)
+			var kinds []TokenKind
+			for _, token := range tokens {
+				kinds = append(kinds, token.Kind)
+			}
+			if len(kinds) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, tokens)
+			}
+			for i := range kinds {
+				if kinds[i] != tt.expected[i] {
+					t.Errorf("Token %d: got %v %q, want %v", i, kinds[i], tokens[i].Text, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTokenizeCSSDialect(t *testing.T) {
+	// `//` isn't a comment in plain CSS, so what follows is still read
+	tokens := Tokenize("a { width: 10px; // 20px\n}", DialectCSS)
+	var dimensions []string
+	for _, token := range tokens {
+		if token.Kind == TokenComment {
+			t.Errorf("Unexpected comment %q", token.Text)
+		}
+		if token.Kind == TokenDimension {
+			dimensions = append(dimensions, token.Text)
+		}
+	}
+	if len(dimensions) != 2 || dimensions[1] != "20px" {
+		t.Errorf("Expected 10px and 20px, got %v", dimensions)
+	}
+
+	if dialect := DialectFor("CSS"); dialect != DialectCSS {
+		t.Errorf("DialectFor(CSS): got %v", dialect)
+	}
+	for _, language := range []string{"scss", "less", "vue"} {
+		if dialect := DialectFor(language); dialect != DialectSCSS {
+			t.Errorf("DialectFor(%s): got %v", language, dialect)
+		}
+	}
+}
+
+func TestTokenizeSassDialect(t *testing.T) {
+	input := "@media (max-width: 768px)\n  .a,\n  .b\n    width: 10px\n    +size(1px,\n      2px)\n.c {\n  margin: 4px\n}\n.d\n  padding: 1px"
+	// the tokens implied by the indentation are written as <{>, <;> and <}>
+	expected := "@media ( max-width : 768px ) <{> . a , . b <{> width : 10px <;> + size( 1px , 2px ) <;> <}> <}> " +
+		". c { margin : 4px <;> } . d <{> padding : 1px <}>"
+	implied := map[TokenKind]string{TokenOpenBrace: "<{>", TokenSemicolon: "<;>", TokenCloseBrace: "<}>"}
+
+	var texts []string
+	for _, token := range Tokenize(input, DialectSass) {
+		switch {
+		case token.Kind == TokenWhitespace || token.Kind == TokenComment:
+		case token.Text == "":
+			texts = append(texts, implied[token.Kind])
+		default:
+			texts = append(texts, token.Text)
+		}
+	}
+	if got := strings.Join(texts, " "); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+
+	for _, language := range []string{"sass", "stylus"} {
+		if dialect := DialectFor(language); dialect != DialectSass {
+			t.Errorf("DialectFor(%s): got %v", language, dialect)
+		}
+	}
+}
+
+func TestTokenizeDimensions(t *testing.T) {
+	tests := []struct {
+		input  string
+		number string
+		unit   string
+	}{
+		{"10px", "10", "px"},
+		{"-10.5px", "-10.5", "px"},
+		{"+.5rem", "+.5", "rem"},
+		{"2em", "2", "em"},
+		{"1e3px", "1e3", "px"},
+		{"10pxs", "10", "pxs"},
+	}
+
+	for _, tt := range tests {
+		tokens := Tokenize(tt.input, DialectSCSS)
+		if len(tokens) != 1 || tokens[0].Kind != TokenDimension {
+			t.Errorf("%q: expected one dimension, got %v", tt.input, tokens)
+			continue
+		}
+		if tokens[0].Number != tt.number || tokens[0].Unit != tt.unit {
+			t.Errorf("%q: got %q %q, want %q %q", tt.input, tokens[0].Number, tokens[0].Unit, tt.number, tt.unit)
+		}
+	}
+}
+
+func TestTokenizeCoversText(t *testing.T) {
+	inputs := []string{
+		"",
+		".a { width: 10px; /* 2px */ }\n",
+		"@media (min-width: 768px) { .b { margin: -1px 0 } }",
+		"url(", "'", "/*", "#{", "@{a", `\`, "1e", "1e+", "-", "--", "-.", "é 10px",
+	}
+
+	for _, input := range inputs {
+		end := 0
+		for _, token := range Tokenize(input, DialectSCSS) {
+			if token.Start != end || token.End <= token.Start || input[token.Start:token.End] != token.Text {
+				t.Errorf("%q: token %+v doesn't continue at %d", input, token, end)
+			}
+			end = token.End
+		}
+		if end != len(input) {
+			t.Errorf("%q: tokens end at %d of %d", input, end, len(input))
+		}
+	}
+}
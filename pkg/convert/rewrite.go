@@ -0,0 +1,92 @@
+package convert
+
+import (
+	"sort"
+	"strings"
+)
+
+// Edit replaces the bytes Start:End of a buffer with NewText
+type Edit struct {
+	Start      int
+	End        int
+	NewText    string
+	Conversion Conversion
+}
+
+// PxToVwEdits returns an edit converting every px literal in text that
+// isn't ignored or excluded, in order
+func (c *Converter) PxToVwEdits(text string) []Edit {
+	return c.PxToVwEditsOf(c.findUnits(text))
+}
+
+// PxToVwEditsOf is PxToVwEdits for literals already found, e.g. by a Scan
+func (c *Converter) PxToVwEditsOf(matches []Match) []Edit {
+	var edits []Edit
+	for _, match := range matches {
+		if match.Unit != "px" || c.Ignored(match) || c.Excluded(match) {
+			continue
+		}
+		conv := c.PxToVwConversion(match)
+		edits = append(edits, Edit{
+			Start:      match.Start,
+			End:        match.End,
+			NewText:    c.Replacement(conv),
+			Conversion: conv,
+		})
+	}
+	return edits
+}
+
+// VwToPxEdits returns an edit converting every vw literal in text that
+// isn't ignored or excluded back to px, in order. Unlike Conversions it doesn't depend
+// on the Vw setting.
+func (c *Converter) VwToPxEdits(text string) []Edit {
+	var edits []Edit
+	for _, match := range c.findUnits(text) {
+		if match.Unit != "vw" || c.Ignored(match) || c.Excluded(match) {
+			continue
+		}
+		conv := c.VwToPxConversion(match)
+		edits = append(edits, Edit{
+			Start:      match.Start,
+			End:        match.End,
+			NewText:    c.Replacement(conv),
+			Conversion: conv,
+		})
+	}
+	return edits
+}
+
+// findUnits returns the literals of text in the dialect of the config
+func (c *Converter) findUnits(text string) []Match {
+	return NewScan(text, c.config.Dialect).Matches
+}
+
+// ConvertText rewrites every px literal in text to vw and returns the new
+// text with the number of values converted
+func (c *Converter) ConvertText(text string) (string, int) {
+	edits := c.PxToVwEdits(text)
+	return Apply(text, edits), len(edits)
+}
+
+// ConvertTextToPx rewrites every vw literal in text to px, the reverse of ConvertText
+func (c *Converter) ConvertTextToPx(text string) (string, int) {
+	edits := c.VwToPxEdits(text)
+	return Apply(text, edits), len(edits)
+}
+
+// Apply returns text with edits applied. Edits must not overlap.
+func Apply(text string, edits []Edit) string {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var b strings.Builder
+	last := 0
+	for _, edit := range sorted {
+		b.WriteString(text[last:edit.Start])
+		b.WriteString(edit.NewText)
+		last = edit.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package convert
+
+import "sort"
+
+// Scan is a text with its tokens, unit literals and ranges found once, for
+// repeated lookups by offset, e.g. by an editor between changes. Lookups
+// read the whole text, so a literal is only found as it stands in the text.
+type Scan struct {
+	Text    string
+	Tokens  []Token
+	Matches []Match
+	Ranges  []Range
+}
+
+// NewScan tokenizes text in a dialect and finds its unit literals and ranges
+func NewScan(text string, dialect Dialect) *Scan {
+	tokens := Tokenize(text, dialect)
+	matches := findUnits(text, tokens)
+	return &Scan{Text: text, Tokens: tokens, Matches: matches, Ranges: findRanges(text, matches)}
+}
+
+// Blocks returns the blocks of the text, like Blocks
+func (s *Scan) Blocks() []Block {
+	return findBlocks(s.Text, s.Tokens)
+}
+
+// UnitAt returns the unit literal touching the byte offset, if any
+func (s *Scan) UnitAt(offset int) (Match, bool) {
+	i := sort.Search(len(s.Matches), func(i int) bool { return s.Matches[i].End >= offset })
+	if i < len(s.Matches) && s.Matches[i].Start <= offset {
+		return s.Matches[i], true
+	}
+	return Match{}, false
+}
+
+// UnitBefore returns the unit literal ending exactly at the byte offset, if any
+func (s *Scan) UnitBefore(offset int) (Match, bool) {
+	i := sort.Search(len(s.Matches), func(i int) bool { return s.Matches[i].End >= offset })
+	if i < len(s.Matches) && s.Matches[i].End == offset {
+		return s.Matches[i], true
+	}
+	return Match{}, false
+}
+
+// RangeAt returns the range touching the byte offset, if any
+func (s *Scan) RangeAt(offset int) (Range, bool) {
+	i := sort.Search(len(s.Ranges), func(i int) bool { return s.Ranges[i].End >= offset })
+	if i < len(s.Ranges) && s.Ranges[i].Start <= offset {
+		return s.Ranges[i], true
+	}
+	return Range{}, false
+}
+
+// RangeBefore returns the range ending exactly at the byte offset, if any
+func (s *Scan) RangeBefore(offset int) (Range, bool) {
+	i := sort.Search(len(s.Ranges), func(i int) bool { return s.Ranges[i].End >= offset })
+	if i < len(s.Ranges) && s.Ranges[i].End == offset {
+		return s.Ranges[i], true
+	}
+	return Range{}, false
+}
+
+// RangeIn returns the range between the byte offsets start and end, like a
+// selection: a "14px..20px" range, or any two px literals as in "14px 20px"
+func (s *Scan) RangeIn(start, end int) (Range, bool) {
+	var matches []Match
+	for _, match := range s.Matches {
+		if match.Unit == "px" && match.Start >= start && match.End <= end {
+			matches = append(matches, match)
+		}
+	}
+	if len(matches) != 2 {
+		return Range{}, false
+	}
+	if r, ok := newRange(s.Text, matches[0], matches[1]); ok {
+		return r, true
+	}
+	return Range{From: matches[0], To: matches[1], Start: matches[0].Start, End: matches[1].End}, true
+}
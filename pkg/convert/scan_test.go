@@ -0,0 +1,48 @@
+package convert
+
+import (
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	text := ".a {\n  width: 10px;\n  font-size: 14px..20px;\n}\n@media (max-width: 768px) {}"
+	scan := NewScan(text, DialectSCSS)
+
+	tests := []struct {
+		name     string
+		lookup   func(offset int) (Match, bool)
+		offset   int
+		expected string
+	}{
+		{"At start", scan.UnitAt, 14, "10px"},
+		{"At end", scan.UnitAt, 18, "10px"},
+		{"At nothing", scan.UnitAt, 8, ""},
+		{"Before", scan.UnitBefore, 18, "10px"},
+		{"Before inside a literal", scan.UnitBefore, 16, ""},
+		{"Condition", scan.UnitAt, 68, "768px"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := tt.lookup(tt.offset)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no literal, got %+v", match)
+				}
+				return
+			}
+			if !ok || match.Text() != tt.expected || text[match.Start:match.End] != tt.expected {
+				t.Errorf("Expected %q, got %+v, %v", tt.expected, match, ok)
+			}
+		})
+	}
+
+	if r, ok := scan.RangeBefore(43); !ok || text[r.Start:r.End] != "14px..20px" {
+		t.Errorf("RangeBefore: got %+v, %v", r, ok)
+	}
+	if r, ok := scan.RangeAt(35); !ok || r.To.Value != 20 {
+		t.Errorf("RangeAt: got %+v, %v", r, ok)
+	}
+	if blocks := scan.Blocks(); len(blocks) != 2 || blocks[1].AtRule != "media" {
+		t.Errorf("Blocks: got %+v", blocks)
+	}
+}